@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	goflags "github.com/jessevdk/go-flags"
+)
+
+// command is run instead of the generators when its name is the first argument, e.g. `ango dump-ir -i chatservice.ango`.
+type command struct {
+	name string
+	// run parses the arguments after the command name and runs the command
+	run func(args []string)
+}
+
+// commands holds all commands
+var commands = []*command{
+	{name: "dump-ir", run: runDumpIR},
+	{name: "doc", run: runDoc},
+}
+
+// lookupCommand returns the command with the given name, or nil.
+func lookupCommand(name string) *command {
+	for _, c := range commands {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// parseCommandFlags parses the arguments for a command into data, it exits on invalid arguments.
+func parseCommandFlags(data interface{}, args []string) {
+	args, err := goflags.NewParser(data, goflags.Default).ParseArgs(args)
+	if err != nil {
+		if _, ok := err.(*goflags.Error); !ok {
+			fmt.Printf("Error parsing flags: %s\n", err)
+		}
+		os.Exit(1)
+	}
+	if len(args) > 0 {
+		fmt.Printf("Unexpected argument(s): '%s'\n", strings.Join(args, " "))
+		os.Exit(1)
+	}
+}
+
+// writeCommandOutput writes the output of a command to outputFile, or to stdout when outputFile is empty.
+func writeCommandOutput(outputFile string, forceOverwrite bool, output []byte) error {
+	if outputFile == "" {
+		_, err := os.Stdout.Write(output)
+		return err
+	}
+	f, err := createOutputFile(outputFile, forceOverwrite)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(output)
+	return err
+}
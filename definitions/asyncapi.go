@@ -0,0 +1,290 @@
+package definitions
+
+import (
+	"fmt"
+)
+
+// AsyncAPIVersion is the AsyncAPI specification version of the document returned by Service.AsyncAPI.
+const AsyncAPIVersion = "3.0.0"
+
+// AsyncAPI is an AsyncAPI document. Only the fields used for ango services are available.
+type AsyncAPI struct {
+	AsyncAPI           string                        `json:"asyncapi"`
+	ID                 string                        `json:"id"`
+	Info               AsyncAPIInfo                  `json:"info"`
+	DefaultContentType string                        `json:"defaultContentType"`
+	Channels           map[string]*AsyncAPIChannel   `json:"channels"`
+	Operations         map[string]*AsyncAPIOperation `json:"operations"`
+	Components         AsyncAPIComponents            `json:"components"`
+}
+
+// AsyncAPIInfo holds the title and version of the API.
+type AsyncAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// AsyncAPIChannel is the websocket on which all messages are sent.
+type AsyncAPIChannel struct {
+	Address     string                       `json:"address"`
+	Title       string                       `json:"title,omitempty"`
+	Description string                       `json:"description,omitempty"`
+	Messages    map[string]*AsyncAPIRef      `json:"messages"`
+	Bindings    map[string]map[string]string `json:"bindings,omitempty"`
+}
+
+// AsyncAPIOperation is a procedure, described from the perspective of the server.
+type AsyncAPIOperation struct {
+	Action      string         `json:"action"`
+	Channel     *AsyncAPIRef   `json:"channel"`
+	Title       string         `json:"title,omitempty"`
+	Summary     string         `json:"summary,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Messages    []*AsyncAPIRef `json:"messages"`
+	Reply       *AsyncAPIReply `json:"reply,omitempty"`
+}
+
+// AsyncAPIReply holds the messages sent in reply to the request of an operation.
+type AsyncAPIReply struct {
+	Channel  *AsyncAPIRef   `json:"channel"`
+	Messages []*AsyncAPIRef `json:"messages"`
+}
+
+// AsyncAPIMessage is a message sent on the channel, the payload is the message envelope (see notes/protocol.md).
+type AsyncAPIMessage struct {
+	Name        string      `json:"name"`
+	Title       string      `json:"title,omitempty"`
+	Summary     string      `json:"summary,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Payload     *JSONSchema `json:"payload"`
+}
+
+// AsyncAPIComponents holds the messages and schemas that are referenced in the document.
+type AsyncAPIComponents struct {
+	Schemas  map[string]*JSONSchema      `json:"schemas"`
+	Messages map[string]*AsyncAPIMessage `json:"messages"`
+}
+
+// AsyncAPIRef is a reference to an object in the document.
+type AsyncAPIRef struct {
+	Ref string `json:"$ref"`
+}
+
+// asyncAPIGen holds the document while it is built.
+type asyncAPIGen struct {
+	doc     *AsyncAPI
+	channel string
+	schemas *JSONSchemaGen
+}
+
+// AsyncAPI returns an AsyncAPI 3.0 document for the service. It describes the websocket as a channel,
+// and every procedure as an operation with the messages from notes/protocol.md.
+// Operations are described from the perspective of the server: server procedures are received, client procedures are sent.
+// Types and the data of the messages are in the components, as JSON Schema (see Service.JSONSchema).
+func (s *Service) AsyncAPI() *AsyncAPI {
+	g := &asyncAPIGen{
+		channel: s.Name,
+		schemas: &JSONSchemaGen{RefPrefix: "#/components/schemas/"},
+	}
+	g.doc = &AsyncAPI{
+		AsyncAPI: AsyncAPIVersion,
+		ID:       "urn:ango:" + s.Name,
+		Info: AsyncAPIInfo{
+			Title:       s.Name,
+			Version:     s.Version(),
+			Description: s.Doc,
+		},
+		DefaultContentType: "application/json",
+		Channels: map[string]*AsyncAPIChannel{
+			s.Name: {
+				Address: "/websocket-ango-" + s.Name,
+				Title:   s.Name + " websocket",
+				Description: "The websocket carrying all messages of the service. " +
+					"After the version verification (plain-text, see protocol.md) all messages are JSON, or MessagePack when negotiated. " +
+					"Multiple messages can be sent at once in a \"batch\" message, holding the messages in data.",
+				Messages: make(map[string]*AsyncAPIRef),
+				Bindings: map[string]map[string]string{
+					"ws": {"method": "GET", "bindingVersion": "0.1.0"},
+				},
+			},
+		},
+		Operations: make(map[string]*AsyncAPIOperation),
+		Components: AsyncAPIComponents{
+			Schemas:  make(map[string]*JSONSchema),
+			Messages: make(map[string]*AsyncAPIMessage),
+		},
+	}
+
+	for name, t := range s.Types {
+		if t.Category != Builtin {
+			g.doc.Components.Schemas[name] = g.schemas.TypeDefinition(t)
+		}
+	}
+	g.doc.Components.Schemas["error"] = &JSONSchema{
+		Description: "The error object, set when a procedure failed. The type is e.g. \"errorReturned\", \"procedureUnavailable\" or \"goingAway\".",
+		Type:        "object",
+		Properties: &JSONSchemaProperties{
+			{Name: "type", Schema: &JSONSchema{Type: "string"}},
+			{Name: "message", Schema: &JSONSchema{Type: "string"}},
+		},
+		Required: []string{"type"},
+	}
+	g.doc.Components.Schemas["meta"] = &JSONSchema{
+		Description:          "Out-of-band string values sent with a request, e.g. \"traceparent\" and \"baggage\" for the W3C trace context.",
+		Type:                 "object",
+		AdditionalProperties: &JSONSchema{Type: "string"},
+	}
+
+	g.streamMessages()
+	g.message(&AsyncAPIMessage{
+		Name:        "goingAway",
+		Summary:     "The server is shutting down, sent to all clients.",
+		Description: "Running requests are still answered, new requests are answered with a goingAway error. The server closes the websocket with close code 1001 when all requests are done.",
+		Payload:     envelope("goingAway", nil),
+	})
+	g.doc.Operations["goingAway"] = &AsyncAPIOperation{
+		Action:   "send",
+		Channel:  g.channelRef(),
+		Summary:  "Notify clients that the server is shutting down.",
+		Messages: []*AsyncAPIRef{g.messageRef("goingAway")},
+	}
+
+	for _, p := range sortedProcedures(s.ServerProcedures) {
+		g.procedure("server", p)
+	}
+	for _, p := range sortedProcedures(s.ClientProcedures) {
+		g.procedure("client", p)
+	}
+	return g.doc
+}
+
+// procedure adds the operation for a procedure, and the messages and schemas it uses.
+func (g *asyncAPIGen) procedure(side string, p *Procedure) {
+	name := side + "." + p.Name
+	for _, m := range p.JSONSchemaMessages(g.schemas) {
+		g.doc.Components.Schemas[name+"."+m.Name] = m.Schema
+	}
+	procedure := JSONSchemaProperty{Name: "procedure", Schema: &JSONSchema{Const: p.Name}}
+	meta := JSONSchemaProperty{Name: "meta", Schema: &JSONSchema{Ref: "#/components/schemas/meta"}}
+	request := dataProperty(name + ".request")
+	response := dataProperty(name + ".response")
+
+	// a oneway request isn't answered, it has no cb_id
+	payload := envelope("req", JSONSchemaProperties{procedure, cbIDProperty, request, meta}, "procedure", "cb_id", "data")
+	if p.Oneway {
+		payload = envelope("req", JSONSchemaProperties{procedure, request, meta}, "procedure", "data")
+	}
+	g.message(&AsyncAPIMessage{
+		Name:        name + ".request",
+		Title:       fmt.Sprintf("%s request", p.Name),
+		Summary:     fmt.Sprintf("Call %s procedure %s.", side, p.Name),
+		Description: p.Doc,
+		Payload:     payload,
+	})
+
+	op := &AsyncAPIOperation{
+		Action:      "receive",
+		Channel:     g.channelRef(),
+		Title:       p.Name,
+		Summary:     fmt.Sprintf("Server procedure %s (%s), called by the client.", p.Name, p.Kind()),
+		Description: p.Doc,
+		Messages:    []*AsyncAPIRef{g.messageRef(name + ".request")},
+	}
+	if side == "client" {
+		op.Action = "send"
+		op.Summary = fmt.Sprintf("Client procedure %s (%s), called by the server.", p.Name, p.Kind())
+	}
+	g.doc.Operations[name] = op
+
+	switch {
+	case p.Oneway:
+		// no reply
+	case p.Stream || p.Subscribe:
+		summary := "An item on the stream."
+		if p.Subscribe {
+			summary = "A new value for the subscription."
+		}
+		g.message(&AsyncAPIMessage{
+			Name:    name + ".item",
+			Title:   fmt.Sprintf("%s item", p.Name),
+			Summary: summary,
+			Payload: envelope("item", JSONSchemaProperties{cbIDProperty, response}, "cb_id", "data"),
+		})
+		op.Reply = &AsyncAPIReply{
+			Channel:  g.channelRef(),
+			Messages: []*AsyncAPIRef{g.messageRef(name + ".item"), g.messageRef("end")},
+		}
+	default:
+		g.message(&AsyncAPIMessage{
+			Name:    name + ".response",
+			Title:   fmt.Sprintf("%s response", p.Name),
+			Summary: fmt.Sprintf("The return values of %s, or the error object when it failed.", p.Name),
+			Payload: envelope("res", JSONSchemaProperties{cbIDProperty, response, errorProperty}, "cb_id"),
+		})
+		op.Reply = &AsyncAPIReply{
+			Channel:  g.channelRef(),
+			Messages: []*AsyncAPIRef{g.messageRef(name + ".response")},
+		}
+	}
+}
+
+// streamMessages adds the messages that control streams and subscriptions, they are the same for all procedures.
+func (g *asyncAPIGen) streamMessages() {
+	g.message(&AsyncAPIMessage{
+		Name:    "end",
+		Summary: "The end of a stream or subscription, sent by the producer. The error is set when the stream failed.",
+		Payload: envelope("end", JSONSchemaProperties{cbIDProperty, errorProperty}, "cb_id"),
+	})
+	g.message(&AsyncAPIMessage{
+		Name:    "cancel",
+		Summary: "Stop a stream or unsubscribe, sent by the consumer. The producer answers with an end message.",
+		Payload: envelope("cancel", JSONSchemaProperties{cbIDProperty}, "cb_id"),
+	})
+	credit := JSONSchemaProperty{Name: "data", Schema: &JSONSchema{
+		Type: "object",
+		Properties: &JSONSchemaProperties{
+			{Name: "credit", Schema: &JSONSchema{Type: "integer", Minimum: new(int64)}},
+		},
+		Required: []string{"credit"},
+	}}
+	g.message(&AsyncAPIMessage{
+		Name:    "credit",
+		Summary: "Allow the producer of a stream to send more items, sent by the consumer.",
+		Payload: envelope("credit", JSONSchemaProperties{cbIDProperty, credit}, "cb_id", "data"),
+	})
+}
+
+// Properties of the message envelope, see notes/protocol.md.
+var (
+	cbIDProperty  = JSONSchemaProperty{Name: "cb_id", Schema: &JSONSchema{Type: "integer", Minimum: new(int64)}}
+	errorProperty = JSONSchemaProperty{Name: "error", Schema: &JSONSchema{AnyOf: []*JSONSchema{
+		{Ref: "#/components/schemas/error"},
+		{Type: "null"},
+	}}}
+)
+
+// dataProperty returns the data property of the message envelope, holding the schema with the given name.
+func dataProperty(schemaName string) JSONSchemaProperty {
+	return JSONSchemaProperty{Name: "data", Schema: &JSONSchema{Ref: "#/components/schemas/" + schemaName}}
+}
+
+// envelope returns the schema for a message with the given type and properties. The type and the properties in required are required.
+func envelope(messageType string, properties JSONSchemaProperties, required ...string) *JSONSchema {
+	props := append(JSONSchemaProperties{{Name: "type", Schema: &JSONSchema{Const: messageType}}}, properties...)
+	return &JSONSchema{Type: "object", Properties: &props, Required: append([]string{"type"}, required...)}
+}
+
+// message adds m to the components and the channel.
+func (g *asyncAPIGen) message(m *AsyncAPIMessage) {
+	g.doc.Components.Messages[m.Name] = m
+	g.doc.Channels[g.channel].Messages[m.Name] = &AsyncAPIRef{Ref: "#/components/messages/" + m.Name}
+}
+
+func (g *asyncAPIGen) channelRef() *AsyncAPIRef {
+	return &AsyncAPIRef{Ref: "#/channels/" + g.channel}
+}
+
+func (g *asyncAPIGen) messageRef(name string) *AsyncAPIRef {
+	return &AsyncAPIRef{Ref: "#/channels/" + g.channel + "/messages/" + name}
+}
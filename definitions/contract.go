@@ -0,0 +1,191 @@
+package definitions
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// WireFormat is the revision of the messages sent by generated code, it is part of every wire contract.
+// It must be incremented when generated code changes the protocol in a way that is incompatible with earlier generated code.
+const WireFormat = 1
+
+// WireContract returns the canonical serialization of everything that a client and server must agree on:
+// the service name, all procedures with their kind and parameters, and the definitions of the types they use.
+// Procedures and types are sorted by name, so the result only depends on the definitions and not on their order in the file.
+// Types that aren't used by a procedure, comments and the ango build don't change the contract.
+//
+// Example, for `type point struct { x int; y int }` and `server move(p point)(ok bool)`:
+//
+//	ango-wire 1
+//	service shapes
+//	server move(p point)(ok bool)
+//	type point struct{x int;y int;}
+func (s *Service) WireContract() string {
+	c := &contractWriter{}
+	fmt.Fprintf(&c.b, "ango-wire %d\n", WireFormat)
+	fmt.Fprintf(&c.b, "service %s\n", s.Name)
+	for _, p := range sortedProcedures(s.ServerProcedures) {
+		c.procedure("server", p)
+	}
+	for _, p := range sortedProcedures(s.ClientProcedures) {
+		c.procedure("client", p)
+	}
+	c.types()
+	return c.b.String()
+}
+
+// Version returns the protocol version for the service: the sha256 hash of the wire contract, hex encoded.
+func (s *Service) Version() string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(s.WireContract())))
+}
+
+// WireContract returns the canonical serialization of a single procedure and the types it uses, in the format of Service.WireContract.
+// The side is "server" or "client".
+func (p *Procedure) WireContract(side string) string {
+	c := &contractWriter{}
+	fmt.Fprintf(&c.b, "ango-wire %d\n", WireFormat)
+	c.procedure(side, p)
+	c.types()
+	return c.b.String()
+}
+
+// Signature returns a short hash of the wire contract of the procedure.
+// A procedure with the same signature on both sides can be called, even when the versions of the services differ.
+func (p *Procedure) Signature(side string) string {
+	sum := sha256.Sum256([]byte(p.WireContract(side)))
+	return fmt.Sprintf("%x", sum[:6])
+}
+
+// SignatureSet returns the signatures of all procedures, as advertised during the handshake:
+// "service=name;server=name:signature,...;client=name:signature,...", procedures sorted by name.
+func (s *Service) SignatureSet() string {
+	return "service=" + s.Name + ";server=" + signatureList("server", s.ServerProcedures) + ";client=" + signatureList("client", s.ClientProcedures)
+}
+
+func signatureList(side string, procs map[string]*Procedure) string {
+	entries := make([]string, 0, len(procs))
+	for _, p := range sortedProcedures(procs) {
+		entries = append(entries, p.Name+":"+p.Signature(side))
+	}
+	return strings.Join(entries, ",")
+}
+
+func sortedProcedures(procs map[string]*Procedure) []*Procedure {
+	list := make([]*Procedure, 0, len(procs))
+	for _, p := range procs {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// contractWriter writes a wire contract, it collects the named types that are referenced so their definitions can be written last.
+type contractWriter struct {
+	b     strings.Builder
+	named map[string]*Type
+}
+
+func (c *contractWriter) procedure(side string, p *Procedure) {
+	c.b.WriteString(side)
+	switch {
+	case p.Oneway:
+		c.b.WriteString(" oneway")
+	case p.Stream:
+		c.b.WriteString(" stream")
+	case p.Subscribe:
+		c.b.WriteString(" subscribe")
+	}
+	fmt.Fprintf(&c.b, " %s(", p.Name)
+	c.params(p.Args)
+	c.b.WriteString(")")
+	if len(p.Rets) > 0 {
+		c.b.WriteString("(")
+		c.params(p.Rets)
+		c.b.WriteString(")")
+	}
+	c.b.WriteString("\n")
+}
+
+func (c *contractWriter) params(params Params) {
+	for i, param := range params {
+		if i > 0 {
+			c.b.WriteString(", ")
+		}
+		fmt.Fprintf(&c.b, "%s ", param.Name)
+		c.typeRef(param.Type)
+	}
+}
+
+// typeRef writes a reference to t: the name for builtin and named types, the definition for anonymous types.
+func (c *contractWriter) typeRef(t *Type) {
+	if t.Name == "" {
+		c.typeDefinition(t)
+		return
+	}
+	c.b.WriteString(t.Name)
+	if t.Category != Builtin {
+		if c.named == nil {
+			c.named = make(map[string]*Type)
+		}
+		c.named[t.Name] = t
+	}
+}
+
+func (c *contractWriter) typeDefinition(t *Type) {
+	switch t.Category {
+	case Builtin:
+		c.b.WriteString(t.Name)
+	case Simple:
+		c.typeRef(t.SimpleType)
+	case Slice:
+		c.b.WriteString("[]")
+		c.typeRef(t.SliceElementType)
+	case Map:
+		c.b.WriteString("map[")
+		c.typeRef(t.MapKeyType)
+		c.b.WriteString("]")
+		c.typeRef(t.MapValueType)
+	case Struct:
+		c.b.WriteString("struct{")
+		for _, field := range t.StructFields {
+			fmt.Fprintf(&c.b, "%s ", field.Name)
+			c.typeRef(field.Type)
+			c.b.WriteString(";")
+		}
+		c.b.WriteString("}")
+	default:
+		panic("unknown type category")
+	}
+}
+
+// types writes the definitions of all referenced named types, sorted by name.
+// A definition can reference more named types, these are written as well.
+func (c *contractWriter) types() {
+	definitions := make(map[string]string)
+	for {
+		var name string
+		for n := range c.named {
+			if _, done := definitions[n]; !done {
+				name = n
+				break
+			}
+		}
+		if name == "" {
+			break
+		}
+		d := &contractWriter{named: c.named}
+		d.typeDefinition(c.named[name])
+		definitions[name] = d.b.String()
+	}
+
+	names := make([]string, 0, len(definitions))
+	for name := range definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&c.b, "type %s %s\n", name, definitions[name])
+	}
+}
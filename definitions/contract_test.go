@@ -0,0 +1,183 @@
+package definitions_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GeertJohan/ango/definitions"
+	"github.com/GeertJohan/ango/parser"
+)
+
+const contractSource = `name shapes
+
+// point is a location on the canvas
+type point struct {
+	x int
+	y int
+}
+
+type points []point
+
+type label string
+
+// move moves the cursor
+server move(p point)(ok bool)
+server stream trace(from point)(p point)
+server rename(l label)(ok bool)
+client oneway redraw(all points)
+`
+
+func parseContract(t *testing.T, source string) *definitions.Service {
+	t.Helper()
+	service, err := parser.NewParser(&parser.Config{}).Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("error parsing source: %v\n%s", err, source)
+	}
+	return service
+}
+
+// signatures returns the signatures of all procedures, keyed by side and name.
+func signatures(service *definitions.Service) map[string]string {
+	sigs := make(map[string]string)
+	for name, p := range service.ServerProcedures {
+		sigs["server "+name] = p.Signature("server")
+	}
+	for name, p := range service.ClientProcedures {
+		sigs["client "+name] = p.Signature("client")
+	}
+	return sigs
+}
+
+func TestWireContract(t *testing.T) {
+	service := parseContract(t, contractSource)
+	want := `ango-wire 1
+service shapes
+server move(p point)(ok bool)
+server rename(l label)(ok bool)
+server stream trace(from point)(p point)
+client oneway redraw(all points)
+type label string
+type point struct{x int;y int;}
+type points []point
+`
+	if got := service.WireContract(); got != want {
+		t.Errorf("unexpected wire contract\ngot:\n%s\nwant:\n%s", got, want)
+	}
+
+	move := service.ServerProcedures["move"]
+	wantMove := "ango-wire 1\nserver move(p point)(ok bool)\ntype point struct{x int;y int;}\n"
+	if got := move.WireContract("server"); got != wantMove {
+		t.Errorf("unexpected wire contract for move\ngot:\n%s\nwant:\n%s", got, wantMove)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	base := parseContract(t, contractSource)
+	baseSigs := signatures(base)
+
+	tests := []struct {
+		name string
+		// source is the modified contractSource
+		source string
+		// changed lists the procedures whose signature must change, nil when the version must not change
+		changed []string
+	}{
+		{
+			// types must be defined before they are used
+			name: "reordered definitions",
+			source: `name shapes
+type label string
+type point struct {
+	x int
+	y int
+}
+type points []point
+client oneway redraw(all points)
+server rename(l label)(ok bool)
+server stream trace(from point)(p point)
+server move(p point)(ok bool)
+`,
+		},
+		{
+			name: "edited comments",
+			source: strings.NewReplacer(
+				"// point is a location on the canvas", "// point is a position on the canvas, in pixels",
+				"// move moves the cursor", "// move moves the cursor\n// it returns false when the point is outside the canvas",
+				"type label string", "// label is shown next to the cursor\ntype label string",
+			).Replace(contractSource),
+		},
+		{
+			name:    "struct field type",
+			source:  strings.Replace(contractSource, "\ty int\n", "\ty int64\n", 1),
+			changed: []string{"server move", "server trace", "client redraw"},
+		},
+		{
+			name:    "struct field name",
+			source:  strings.Replace(contractSource, "\ty int\n", "\tz int\n", 1),
+			changed: []string{"server move", "server trace", "client redraw"},
+		},
+		{
+			name:    "struct field added",
+			source:  strings.Replace(contractSource, "\ty int\n", "\ty int\n\tz int\n", 1),
+			changed: []string{"server move", "server trace", "client redraw"},
+		},
+		{
+			name:    "param type",
+			source:  strings.Replace(contractSource, "rename(l label)(ok bool)", "rename(l string)(ok bool)", 1),
+			changed: []string{"server rename"},
+		},
+		{
+			name:    "return type",
+			source:  strings.Replace(contractSource, "move(p point)(ok bool)", "move(p point)(ok int)", 1),
+			changed: []string{"server move"},
+		},
+		{
+			name:    "named type definition",
+			source:  strings.Replace(contractSource, "type label string", "type label int", 1),
+			changed: []string{"server rename"},
+		},
+		{
+			name:    "procedure kind",
+			source:  strings.Replace(contractSource, "server stream trace", "server subscribe trace", 1),
+			changed: []string{"server trace"},
+		},
+		{
+			name:    "procedure kind oneway",
+			source:  strings.Replace(contractSource, "client oneway redraw", "client redraw", 1),
+			changed: []string{"client redraw"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service := parseContract(t, test.source)
+			sigs := signatures(service)
+
+			if len(test.changed) == 0 {
+				if service.Version() != base.Version() {
+					t.Errorf("version changed\ngot contract:\n%s\nwant contract:\n%s", service.WireContract(), base.WireContract())
+				}
+			} else if service.Version() == base.Version() {
+				t.Errorf("version didn't change\ncontract:\n%s", service.WireContract())
+			}
+
+			changed := make(map[string]bool)
+			for _, name := range test.changed {
+				changed[name] = true
+			}
+			for name, sig := range baseSigs {
+				got, ok := sigs[name]
+				if !ok {
+					t.Errorf("procedure %s is missing", name)
+					continue
+				}
+				if changed[name] && got == sig {
+					t.Errorf("signature of %s didn't change", name)
+				}
+				if !changed[name] && got != sig {
+					t.Errorf("signature of %s changed from %s to %s", name, sig, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package definitions
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// The methods in this file create example JSON for documentation (ango-doc.tmpl.md and ango-doc.tmpl.html).
+// The values are like the samples in the generated json tests: strings are "ango", integers 42, floats 4.2 and bools true.
+// Slices and maps hold a single element.
+
+// exampleCallbackID is the cb_id in example messages, the same as in the example in notes/protocol.md.
+const exampleCallbackID = 523
+
+// exampleField is a field of an example object.
+type exampleField struct {
+	name  string
+	value interface{}
+}
+
+// exampleObject is a JSON object that is encoded with its fields in order.
+type exampleObject []exampleField
+
+// MarshalJSON encodes the object with its fields in order.
+func (o exampleObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		name, err := json.Marshal(f.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(name)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// exampleValue returns an example value for type t, as it is encoded by the generated Go code.
+func exampleValue(t *Type) interface{} {
+	u := t.underlying()
+	switch u.Category {
+	case Builtin:
+		switch {
+		case u == TypeString:
+			return "ango"
+		case u == TypeBool:
+			return true
+		case u == TypeFloat32 || u == TypeFloat64:
+			return 4.2
+		default:
+			return 42
+		}
+	case Slice:
+		if u.SliceElementType.underlying() == TypeUint8 {
+			return base64.StdEncoding.EncodeToString([]byte("ango"))
+		}
+		return []interface{}{exampleValue(u.SliceElementType)}
+	case Map:
+		key := u.MapKeyType.underlying()
+		switch {
+		case key.isJSONString():
+			return exampleObject{{"ango", exampleValue(u.MapValueType)}}
+		case key.isJSONInt() || key.isJSONUint():
+			return exampleObject{{"42", exampleValue(u.MapValueType)}}
+		}
+		return exampleObject{}
+	case Struct:
+		o := exampleObject{}
+		for _, f := range u.StructFields {
+			o = append(o, exampleField{strings.ToUpper(f.Name[:1]) + f.Name[1:], exampleValue(f.Type)})
+		}
+		return o
+	}
+	panic("unknown type category")
+}
+
+// exampleParams returns the example data object for params.
+func exampleParams(params Params) exampleObject {
+	o := exampleObject{}
+	for _, p := range params {
+		o = append(o, exampleField{p.Name, exampleValue(p.Type)})
+	}
+	return o
+}
+
+// exampleJSON returns v as indented JSON.
+func exampleJSON(v interface{}) string {
+	b, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
+
+// ExampleJSON returns an example value of the type as indented JSON.
+// Used by ango-doc.tmpl.md and ango-doc.tmpl.html
+func (t *Type) ExampleJSON() string {
+	return exampleJSON(exampleValue(t))
+}
+
+// ExampleRequest returns an example "req" message for the procedure as indented JSON, like the example in notes/protocol.md.
+// Oneway requests have no cb_id.
+// Used by ango-doc.tmpl.md and ango-doc.tmpl.html
+func (p *Procedure) ExampleRequest() string {
+	msg := exampleObject{{"type", "req"}, {"procedure", p.Name}}
+	if !p.Oneway {
+		msg = append(msg, exampleField{"cb_id", exampleCallbackID})
+	}
+	msg = append(msg, exampleField{"data", exampleParams(p.Args)})
+	return exampleJSON(msg)
+}
+
+// ExampleResponse returns an example "res" message for the procedure as indented JSON, or an "item" message for stream and subscribe procedures.
+// Oneway procedures have no response, an empty string is returned.
+// Used by ango-doc.tmpl.md and ango-doc.tmpl.html
+func (p *Procedure) ExampleResponse() string {
+	if p.Oneway {
+		return ""
+	}
+	msgType := "res"
+	if p.Stream || p.Subscribe {
+		msgType = "item"
+	}
+	return exampleJSON(exampleObject{{"type", msgType}, {"cb_id", exampleCallbackID}, {"data", exampleParams(p.Rets)}})
+}
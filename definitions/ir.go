@@ -0,0 +1,151 @@
+package definitions
+
+// IRVersion is the version of the IR format. It is incremented when a change to the format is not backwards compatible,
+// e.g. when a field is removed or its meaning changes. Fields may be added without incrementing the version.
+const IRVersion = 1
+
+// IR is the intermediate representation of a service, a stable JSON document for tools that don't parse .ango files themselves.
+// Types and procedures are sorted by name, so the JSON encoding only changes when the definitions change.
+// The format is described in notes/ir.md.
+type IR struct {
+	IRVersion        int            `json:"irVersion"`
+	Service          string         `json:"service"`
+	Doc              string         `json:"doc,omitempty"`
+	ProtocolVersion  string         `json:"protocolVersion"`
+	WireFormat       int            `json:"wireFormat"`
+	Types            []*IRType      `json:"types"`
+	ServerProcedures []*IRProcedure `json:"serverProcedures"`
+	ClientProcedures []*IRProcedure `json:"clientProcedures"`
+}
+
+// IRType is a type declaration or a reference to a type.
+// A reference to a builtin type only has Builtin set, a reference to a named type only has Ref set.
+// Type declarations and anonymous types have Kind set, and the fields for that kind.
+type IRType struct {
+	Name    string    `json:"name,omitempty"`
+	Doc     string    `json:"doc,omitempty"`
+	Source  *IRSource `json:"source,omitempty"`
+	Builtin string    `json:"builtin,omitempty"`
+	Ref     string    `json:"ref,omitempty"`
+
+	// Kind is "simple", "slice", "map" or "struct"
+	Kind   string     `json:"kind,omitempty"`
+	Type   *IRType    `json:"type,omitempty"`
+	Elem   *IRType    `json:"elem,omitempty"`
+	Key    *IRType    `json:"key,omitempty"`
+	Value  *IRType    `json:"value,omitempty"`
+	Fields []*IRField `json:"fields,omitempty"`
+}
+
+// IRField is a struct field.
+type IRField struct {
+	Name string  `json:"name"`
+	Doc  string  `json:"doc,omitempty"`
+	Type *IRType `json:"type"`
+}
+
+// IRProcedure is a server or client procedure.
+type IRProcedure struct {
+	Name string `json:"name"`
+	Doc  string `json:"doc,omitempty"`
+	// Kind is "call", "oneway", "stream" or "subscribe"
+	Kind      string     `json:"kind"`
+	Signature string     `json:"signature"`
+	Source    *IRSource  `json:"source"`
+	Args      []*IRParam `json:"args"`
+	Rets      []*IRParam `json:"rets"`
+}
+
+// IRParam is an argument or return value.
+type IRParam struct {
+	Name string  `json:"name"`
+	Type *IRType `json:"type"`
+}
+
+// IRSource is the location of a definition in the .ango file.
+type IRSource struct {
+	Line int `json:"line"`
+}
+
+// IR returns the intermediate representation of the service.
+func (s *Service) IR() *IR {
+	ir := &IR{
+		IRVersion:        IRVersion,
+		Service:          s.Name,
+		Doc:              s.Doc,
+		ProtocolVersion:  s.Version(),
+		WireFormat:       WireFormat,
+		Types:            []*IRType{},
+		ServerProcedures: irProcedures("server", s.ServerProcedures),
+		ClientProcedures: irProcedures("client", s.ClientProcedures),
+	}
+
+	for _, t := range s.NamedTypes() {
+		decl := irTypeDefinition(t)
+		decl.Name = t.Name
+		decl.Doc = t.Doc
+		decl.Source = &IRSource{Line: t.Source.Linenumber}
+		ir.Types = append(ir.Types, decl)
+	}
+	return ir
+}
+
+// irProcedures returns the procedures sorted by name.
+func irProcedures(side string, procs map[string]*Procedure) []*IRProcedure {
+	list := []*IRProcedure{}
+	for _, p := range sortedProcedures(procs) {
+		list = append(list, &IRProcedure{
+			Name:      p.Name,
+			Doc:       p.Doc,
+			Kind:      p.Kind(),
+			Signature: p.Signature(side),
+			Source:    &IRSource{Line: p.Source.Linenumber},
+			Args:      irParams(p.Args),
+			Rets:      irParams(p.Rets),
+		})
+	}
+	return list
+}
+
+func irParams(params Params) []*IRParam {
+	list := []*IRParam{}
+	for _, p := range params {
+		list = append(list, &IRParam{Name: p.Name, Type: irTypeRef(p.Type)})
+	}
+	return list
+}
+
+// irTypeRef returns a reference to t, or the definition when t is anonymous.
+// An anonymous simple type, e.g. the type of a struct field `x int`, is a reference to the type it is defined as.
+func irTypeRef(t *Type) *IRType {
+	switch {
+	case t.Category == Builtin:
+		return &IRType{Builtin: t.Name}
+	case t.Name != "":
+		return &IRType{Ref: t.Name}
+	case t.Category == Simple:
+		return irTypeRef(t.SimpleType)
+	default:
+		return irTypeDefinition(t)
+	}
+}
+
+// irTypeDefinition returns the definition of t, referring to the types it is defined with.
+func irTypeDefinition(t *Type) *IRType {
+	switch t.Category {
+	case Simple:
+		return &IRType{Kind: "simple", Type: irTypeRef(t.SimpleType)}
+	case Slice:
+		return &IRType{Kind: "slice", Elem: irTypeRef(t.SliceElementType)}
+	case Map:
+		return &IRType{Kind: "map", Key: irTypeRef(t.MapKeyType), Value: irTypeRef(t.MapValueType)}
+	case Struct:
+		fields := []*IRField{}
+		for _, f := range t.StructFields {
+			fields = append(fields, &IRField{Name: f.Name, Doc: f.Doc, Type: irTypeRef(f.Type)})
+		}
+		return &IRType{Kind: "struct", Fields: fields}
+	default:
+		panic("unknown type category")
+	}
+}
@@ -0,0 +1,580 @@
+package definitions
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The methods in this file generate the bodies for the hand-rolled JSON encode and decode functions.
+// The generated code uses the jsonEncoder and jsonDecoder types defined in ango-json.tmpl.go,
+// and produces the same JSON as encoding/json does for the generated types.
+
+// jsonSampleDepth limits the nesting of generated sample values, types can be recursive through slices and maps.
+const jsonSampleDepth = 3
+
+// jsonGen writes generated code, it keeps a counter for unique variable names.
+type jsonGen struct {
+	b strings.Builder
+	n int
+}
+
+func (g *jsonGen) line(format string, args ...interface{}) {
+	fmt.Fprintf(&g.b, format+"\n", args...)
+}
+
+// v returns a new unique variable name with prefix.
+func (g *jsonGen) v(prefix string) string {
+	g.n++
+	return fmt.Sprintf("%s%d", prefix, g.n)
+}
+
+// goType returns the go type for a value of type t, as used in generated code.
+func goType(t *Type) string {
+	if t.Category == Builtin || t.Name != "" {
+		return t.GoName()
+	}
+	return t.GoTypeDefinition()
+}
+
+// underlying follows simple types until the type that defines the value.
+func (t *Type) underlying() *Type {
+	for t.Category == Simple {
+		t = t.SimpleType
+	}
+	return t
+}
+
+// GoJSONMethods returns true when MarshalJSON and UnmarshalJSON methods are generated for the type.
+// Types that are a builtin type underneath are encoded directly by encoding/json.
+// Used by ango-json.tmpl.go
+func (t *Type) GoJSONMethods() bool {
+	return t.Category != Builtin && t.underlying().Category != Builtin
+}
+
+// GoJSONEncode returns the body for `func jsonEncodeT(e *jsonEncoder, v *T)`
+// Used by ango-json.tmpl.go
+func (t *Type) GoJSONEncode() string {
+	g := &jsonGen{}
+	switch t.Category {
+	case Simple:
+		if t.SimpleType.Category == Builtin {
+			g.encodeBuiltin("(*v)", t.SimpleType)
+		} else {
+			g.line("jsonEncode%s(e, (*%s)(v))", t.SimpleType.CapitalizedName(), t.SimpleType.GoName())
+		}
+	case Struct:
+		g.encodeStruct("v", t)
+	default:
+		g.encode("(*v)", t, true)
+	}
+	return g.b.String()
+}
+
+// GoJSONDecode returns the body for `func jsonDecodeT(d *jsonDecoder, v *T)`
+// Used by ango-json.tmpl.go
+func (t *Type) GoJSONDecode() string {
+	g := &jsonGen{}
+	switch t.Category {
+	case Simple:
+		if t.SimpleType.Category == Builtin {
+			g.decodeBuiltin("(*v)", t.GoName(), t.SimpleType)
+		} else {
+			g.line("jsonDecode%s(d, (*%s)(v))", t.SimpleType.CapitalizedName(), t.SimpleType.GoName())
+		}
+	case Struct:
+		g.decodeStruct("v", t)
+	default:
+		g.decode("(*v)", t.GoName(), t, true)
+	}
+	return g.b.String()
+}
+
+// GoJSONSample returns an expression creating a sample value of type t, for benchmarks.
+// Used by ango-json.tmpl.go
+func (t *Type) GoJSONSample() string {
+	return jsonSample(t.GoName(), t, 0)
+}
+
+// GoJSONEncode returns the body for `func (v *angoDataStruct) MarshalJSON()` for a struct holding the params.
+// Used by ango-json.tmpl.go
+func (ps Params) GoJSONEncode() string {
+	g := &jsonGen{}
+	if len(ps) == 0 {
+		g.line("e.raw(`{}`)")
+		return g.b.String()
+	}
+	for i, p := range ps {
+		sep := ","
+		if i == 0 {
+			sep = "{"
+		}
+		g.line("e.raw(`%s\"%s\":`)", sep, p.Name)
+		g.encodeParam("v."+p.CapitalizedName(), p.Type)
+	}
+	g.line("e.byte('}')")
+	return g.b.String()
+}
+
+// GoJSONDecode returns the body for `func (v *angoDataStruct) UnmarshalJSON()` for a struct holding the params.
+// Used by ango-json.tmpl.go
+func (ps Params) GoJSONDecode() string {
+	g := &jsonGen{}
+	names := make([]string, 0, len(ps))
+	for _, p := range ps {
+		names = append(names, `"`+p.Name+`"`)
+	}
+	g.line("if !d.null() && d.objectStart() {")
+	g.line("for d.objectNext() {")
+	if len(ps) == 0 {
+		g.line("d.skip()")
+	} else {
+		g.line("switch jsonField(d.key, %s) {", strings.Join(names, ", "))
+		for i, p := range ps {
+			g.line("case %d:", i)
+			g.decodeParam("v."+p.CapitalizedName(), p.Type)
+		}
+		g.line("default:")
+		g.line("d.skip()")
+		g.line("}")
+	}
+	g.line("}")
+	g.line("}")
+	return g.b.String()
+}
+
+// GoJSONSample returns the fields for a composite literal creating a sample value of the struct holding the params, for benchmarks.
+// Used by ango-json.tmpl.go
+func (ps Params) GoJSONSample() string {
+	fields := make([]string, 0, len(ps))
+	for _, p := range ps {
+		sample := jsonSample(p.Type.GoName(), p.Type, 0)
+		if p.Type.Category == Struct {
+			sample = "&" + sample
+		}
+		fields = append(fields, p.CapitalizedName()+": "+sample)
+	}
+	return strings.Join(fields, ", ")
+}
+
+// encodeParam writes the code to encode a param value, struct params are pointers.
+func (g *jsonGen) encodeParam(expr string, t *Type) {
+	if t.Category == Struct {
+		g.line("if %s == nil {", expr)
+		g.line("e.null()")
+		g.line("} else {")
+		g.line("jsonEncode%s(e, %s)", t.CapitalizedName(), expr)
+		g.line("}")
+		return
+	}
+	g.encode(expr, t, false)
+}
+
+// decodeParam writes the code to decode a param value, struct params are pointers.
+func (g *jsonGen) decodeParam(expr string, t *Type) {
+	if t.Category == Struct {
+		g.line("if d.null() {")
+		g.line("%s = nil", expr)
+		g.line("} else {")
+		g.line("if %s == nil {", expr)
+		g.line("%s = new(%s)", expr, t.GoName())
+		g.line("}")
+		g.line("jsonDecode%s(d, %s)", t.CapitalizedName(), expr)
+		g.line("}")
+		return
+	}
+	g.decode(expr, goType(t), t, false)
+}
+
+// encode writes the code to encode the addressable expr holding a value of type t.
+// When inline is false and t is a named type, the encode function for the named type is called.
+func (g *jsonGen) encode(expr string, t *Type, inline bool) {
+	if t.Category == Builtin {
+		g.encodeBuiltin(expr, t)
+		return
+	}
+	if t.Name != "" && !inline {
+		g.line("jsonEncode%s(e, &%s)", t.CapitalizedName(), expr)
+		return
+	}
+	switch t.Category {
+	case Simple:
+		g.encode(expr, t.SimpleType, false)
+	case Slice:
+		g.line("if %s == nil {", expr)
+		g.line("e.null()")
+		if elem := t.SliceElementType.underlying(); elem == TypeUint8 {
+			// like encoding/json, byte slices are encoded as base64 string
+			g.line("} else {")
+			if t.SliceElementType == TypeUint8 {
+				g.line("e.bytes([]byte(%s))", expr)
+			} else {
+				b := g.v("b")
+				g.line("%s := make([]byte, len(%s))", b, expr)
+				g.line("for i, c := range %s {", expr)
+				g.line("%s[i] = byte(c)", b)
+				g.line("}")
+				g.line("e.bytes(%s)", b)
+			}
+			g.line("}")
+			return
+		}
+		i := g.v("i")
+		g.line("} else {")
+		g.line("e.byte('[')")
+		g.line("for %s := range %s {", i, expr)
+		g.line("if %s > 0 {", i)
+		g.line("e.byte(',')")
+		g.line("}")
+		g.encode(expr+"["+i+"]", t.SliceElementType, false)
+		g.line("}")
+		g.line("e.byte(']')")
+		g.line("}")
+	case Map:
+		key := t.MapKeyType.underlying()
+		keys, k, val := g.v("keys"), g.v("k"), g.v("val")
+		g.line("if %s == nil {", expr)
+		g.line("e.null()")
+		g.line("} else {")
+		if !key.isJSONString() && !key.isJSONInt() && !key.isJSONUint() {
+			g.line("if len(%s) == 0 {", expr)
+			g.line("e.raw(`{}`)")
+			g.line("} else {")
+			g.line("e.fail(errors.New(\"json: unsupported map key type %s\"))", goType(t.MapKeyType))
+			g.line("}")
+			g.line("}")
+			return
+		}
+		// like encoding/json, keys are sorted by their string representation
+		g.line("%s := make([]%s, 0, len(%s))", keys, goType(t.MapKeyType), expr)
+		g.line("for %s := range %s {", k, expr)
+		g.line("%s = append(%s, %s)", keys, keys, k)
+		g.line("}")
+		switch {
+		case key.isJSONString():
+			g.line("sort.Slice(%s, func(i, j int) bool { return %s[i] < %s[j] })", keys, keys, keys)
+		case key.isJSONInt():
+			g.line("sort.Slice(%s, func(i, j int) bool { return jsonIntKeyLess(int64(%s[i]), int64(%s[j])) })", keys, keys, keys)
+		case key.isJSONUint():
+			g.line("sort.Slice(%s, func(i, j int) bool { return jsonUintKeyLess(uint64(%s[i]), uint64(%s[j])) })", keys, keys, keys)
+		}
+		g.line("e.byte('{')")
+		g.line("for i, %s := range %s {", k, keys)
+		g.line("if i > 0 {")
+		g.line("e.byte(',')")
+		g.line("}")
+		switch {
+		case key.isJSONString():
+			g.line("e.string(string(%s))", k)
+		case key.isJSONInt():
+			g.line("e.byte('\"')")
+			g.line("e.int(int64(%s))", k)
+			g.line("e.byte('\"')")
+		case key.isJSONUint():
+			g.line("e.byte('\"')")
+			g.line("e.uint(uint64(%s))", k)
+			g.line("e.byte('\"')")
+		}
+		g.line("e.byte(':')")
+		g.line("%s := %s[%s]", val, expr, k)
+		g.encode(val, t.MapValueType, false)
+		g.line("}")
+		g.line("e.byte('}')")
+		g.line("}")
+	case Struct:
+		g.encodeStruct(expr, t)
+	}
+}
+
+// encodeStruct writes the code to encode the struct expr. Fields are named like encoding/json names them.
+func (g *jsonGen) encodeStruct(expr string, t *Type) {
+	if len(t.StructFields) == 0 {
+		g.line("e.raw(`{}`)")
+		return
+	}
+	for i, f := range t.StructFields {
+		sep := ","
+		if i == 0 {
+			sep = "{"
+		}
+		name := strings.ToUpper(f.Name[:1]) + f.Name[1:]
+		g.line("e.raw(`%s\"%s\":`)", sep, name)
+		g.encode(expr+"."+name, f.Type, false)
+	}
+	g.line("e.byte('}')")
+}
+
+func (g *jsonGen) encodeBuiltin(expr string, t *Type) {
+	switch {
+	case t == TypeString:
+		g.line("e.string(string(%s))", expr)
+	case t == TypeBool:
+		g.line("e.bool(bool(%s))", expr)
+	case t.isJSONInt():
+		g.line("e.int(int64(%s))", expr)
+	case t.isJSONUint():
+		g.line("e.uint(uint64(%s))", expr)
+	case t == TypeFloat32:
+		g.line("e.float(float64(%s), 32)", expr)
+	case t == TypeFloat64:
+		g.line("e.float(float64(%s), 64)", expr)
+	default:
+		panic("unknown builtin type " + t.Name)
+	}
+}
+
+// decode writes the code to decode into the addressable expr of go type typ, holding a value of type t.
+// When inline is false and t is a named type, the decode function for the named type is called.
+func (g *jsonGen) decode(expr string, typ string, t *Type, inline bool) {
+	if t.Category == Builtin {
+		g.decodeBuiltin(expr, typ, t)
+		return
+	}
+	if t.Name != "" && !inline {
+		g.line("jsonDecode%s(d, &%s)", t.CapitalizedName(), expr)
+		return
+	}
+	switch t.Category {
+	case Simple:
+		g.decode(expr, typ, t.SimpleType, false)
+	case Slice:
+		g.line("if d.null() {")
+		g.line("%s = nil", expr)
+		if elem := t.SliceElementType.underlying(); elem == TypeUint8 {
+			b := g.v("b")
+			g.line("} else if %s := d.bytes(); %s != nil {", b, b)
+			if t.SliceElementType == TypeUint8 {
+				g.line("%s = %s", expr, b)
+			} else {
+				g.line("%s = make(%s, len(%s))", expr, typ, b)
+				g.line("for i, c := range %s {", b)
+				g.line("%s[i] = %s(c)", expr, goType(t.SliceElementType))
+				g.line("}")
+			}
+			g.line("}")
+			return
+		}
+		s, elem := g.v("s"), g.v("elem")
+		g.line("} else if d.arrayStart() {")
+		g.line("%s := %s[:0]", s, expr)
+		g.line("if %s == nil {", s)
+		g.line("%s = %s{}", s, typ)
+		g.line("}")
+		g.line("for d.arrayNext() {")
+		g.line("var %s %s", elem, goType(t.SliceElementType))
+		g.decode(elem, goType(t.SliceElementType), t.SliceElementType, false)
+		g.line("%s = append(%s, %s)", s, s, elem)
+		g.line("}")
+		g.line("%s = %s", expr, s)
+		g.line("}")
+	case Map:
+		key := t.MapKeyType.underlying()
+		k, val := g.v("k"), g.v("val")
+		g.line("if d.null() {")
+		g.line("%s = nil", expr)
+		g.line("} else if d.objectStart() {")
+		g.line("if %s == nil {", expr)
+		g.line("%s = make(%s)", expr, typ)
+		g.line("}")
+		g.line("for d.objectNext() {")
+		switch {
+		case key.isJSONString():
+			g.line("%s := %s(d.key)", k, goType(t.MapKeyType))
+		case key.isJSONInt():
+			g.line("%s := %s(d.keyInt(%d))", k, goType(t.MapKeyType), key.bits())
+		case key.isJSONUint():
+			g.line("%s := %s(d.keyUint(%d))", k, goType(t.MapKeyType), key.bits())
+		default:
+			g.line("var %s %s", k, goType(t.MapKeyType))
+			g.line("d.fail(errors.New(\"json: unsupported map key type %s\"))", goType(t.MapKeyType))
+		}
+		g.line("var %s %s", val, goType(t.MapValueType))
+		g.decode(val, goType(t.MapValueType), t.MapValueType, false)
+		g.line("%s[%s] = %s", expr, k, val)
+		g.line("}")
+		g.line("}")
+	case Struct:
+		g.decodeStruct(expr, t)
+	}
+}
+
+// decodeStruct writes the code to decode into the struct expr. Like encoding/json, field names are matched
+// exactly or case-insensitive, unknown fields are skipped and null leaves the struct unchanged.
+func (g *jsonGen) decodeStruct(expr string, t *Type) {
+	g.line("if !d.null() && d.objectStart() {")
+	g.line("for d.objectNext() {")
+	if len(t.StructFields) == 0 {
+		g.line("d.skip()")
+	} else {
+		names := make([]string, 0, len(t.StructFields))
+		for _, f := range t.StructFields {
+			names = append(names, `"`+strings.ToUpper(f.Name[:1])+f.Name[1:]+`"`)
+		}
+		g.line("switch jsonField(d.key, %s) {", strings.Join(names, ", "))
+		for i, f := range t.StructFields {
+			name := strings.ToUpper(f.Name[:1]) + f.Name[1:]
+			g.line("case %d:", i)
+			g.decode(expr+"."+name, goType(f.Type), f.Type, false)
+		}
+		g.line("default:")
+		g.line("d.skip()")
+		g.line("}")
+	}
+	g.line("}")
+	g.line("}")
+}
+
+func (g *jsonGen) decodeBuiltin(expr string, typ string, t *Type) {
+	g.line("if !d.null() {")
+	switch {
+	case t == TypeString:
+		g.line("%s = %s(d.string())", expr, typ)
+	case t == TypeBool:
+		g.line("%s = %s(d.bool())", expr, typ)
+	case t.isJSONInt():
+		g.line("%s = %s(d.int(%d))", expr, typ, t.bits())
+	case t.isJSONUint():
+		g.line("%s = %s(d.uint(%d))", expr, typ, t.bits())
+	case t == TypeFloat32:
+		g.line("%s = %s(d.float(32))", expr, typ)
+	case t == TypeFloat64:
+		g.line("%s = %s(d.float(64))", expr, typ)
+	default:
+		panic("unknown builtin type " + t.Name)
+	}
+	g.line("}")
+}
+
+func (t *Type) isJSONString() bool {
+	return t == TypeString
+}
+
+func (t *Type) isJSONInt() bool {
+	switch t {
+	case TypeInt, TypeInt8, TypeInt16, TypeInt32, TypeInt64:
+		return true
+	}
+	return false
+}
+
+func (t *Type) isJSONUint() bool {
+	switch t {
+	case TypeUint, TypeUint8, TypeUint16, TypeUint32, TypeUint64:
+		return true
+	}
+	return false
+}
+
+// bits returns the size for a builtin integer type, 0 for int and uint.
+func (t *Type) bits() int {
+	switch t {
+	case TypeInt8, TypeUint8:
+		return 8
+	case TypeInt16, TypeUint16:
+		return 16
+	case TypeInt32, TypeUint32:
+		return 32
+	case TypeInt64, TypeUint64:
+		return 64
+	}
+	return 0
+}
+
+// jsonSample returns an expression of go type typ with a sample value of type t.
+func jsonSample(typ string, t *Type, depth int) string {
+	u := t.underlying()
+	switch u.Category {
+	case Builtin:
+		switch {
+		case u == TypeString:
+			return typ + `("ango")`
+		case u == TypeBool:
+			return typ + "(true)"
+		case u == TypeFloat32 || u == TypeFloat64:
+			return typ + "(4.2)"
+		default:
+			return typ + "(42)"
+		}
+	case Slice:
+		if depth >= jsonSampleDepth {
+			return typ + "{}"
+		}
+		elem := jsonSample(goType(u.SliceElementType), u.SliceElementType, depth+1)
+		return typ + "{" + elem + ", " + elem + ", " + elem + "}"
+	case Map:
+		if depth >= jsonSampleDepth {
+			return typ + "{}"
+		}
+		key := u.MapKeyType.underlying()
+		val := jsonSample(goType(u.MapValueType), u.MapValueType, depth+1)
+		switch {
+		case key.isJSONString():
+			return typ + `{"a": ` + val + `, "b": ` + val + `, "c": ` + val + "}"
+		case key.isJSONInt() || key.isJSONUint():
+			return typ + "{1: " + val + ", 2: " + val + ", 3: " + val + "}"
+		}
+		return typ + "{}"
+	case Struct:
+		if depth >= jsonSampleDepth {
+			return typ + "{}"
+		}
+		fields := make([]string, 0, len(u.StructFields))
+		for _, f := range u.StructFields {
+			name := strings.ToUpper(f.Name[:1]) + f.Name[1:]
+			fields = append(fields, name+": "+jsonSample(goType(f.Type), f.Type, depth+1))
+		}
+		return typ + "{" + strings.Join(fields, ", ") + "}"
+	}
+	panic("unknown type category")
+}
+
+// JSONParamsData holds the name of a generated struct holding params, and the params.
+type JSONParamsData struct {
+	Name string
+	// TestName is the name without prefix and suffix, used for tests and benchmarks
+	TestName string
+	Params   Params
+}
+
+// jsonParamsData returns the data for a struct named prefix+CapitalizedName.
+// The test name is CapitalizedName prefixed by the part between ango and Data in prefix.
+func (p *Procedure) jsonParamsData(prefix string, params Params) *JSONParamsData {
+	return &JSONParamsData{
+		Name:     prefix + p.CapitalizedName(),
+		TestName: strings.TrimSuffix(strings.TrimPrefix(prefix, "ango"), "Data") + p.CapitalizedName(),
+		Params:   params,
+	}
+}
+
+// GoJSONArgsData returns the data to generate JSON methods for the args struct, named prefix+CapitalizedName.
+// Used by ango-json.tmpl.go
+func (p *Procedure) GoJSONArgsData(prefix string) *JSONParamsData {
+	return p.jsonParamsData(prefix, p.Args)
+}
+
+// GoJSONRetsData returns the data to generate JSON methods for the rets struct, named prefix+CapitalizedName.
+// Used by ango-json.tmpl.go
+func (p *Procedure) GoJSONRetsData(prefix string) *JSONParamsData {
+	return p.jsonParamsData(prefix, p.Rets)
+}
+
+// JSONTestData holds the data to generate a test and benchmarks for the generated JSON methods of a type.
+type JSONTestData struct {
+	// Name is used in the test and benchmark names
+	Name string
+	// Type is the go type
+	Type string
+	// Sample is a composite literal of Type
+	Sample string
+}
+
+// GoJSONTest returns the data to generate a test and benchmarks for the type.
+// Used by ango-json-test.tmpl.go
+func (t *Type) GoJSONTest() *JSONTestData {
+	return &JSONTestData{Name: t.CapitalizedName(), Type: t.GoName(), Sample: t.GoJSONSample()}
+}
+
+// Test returns the data to generate a test and benchmarks for the struct holding the params.
+// Used by ango-json-test.tmpl.go
+func (d *JSONParamsData) Test() *JSONTestData {
+	return &JSONTestData{Name: d.TestName, Type: d.Name, Sample: d.Name + "{" + d.Params.GoJSONSample() + "}"}
+}
@@ -0,0 +1,241 @@
+package definitions
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// JSONSchemaDraft is the JSON Schema dialect of the schemas returned by Service.JSONSchema.
+const JSONSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema is a JSON Schema. Only the keywords used for ango types are available.
+// Fields are encoded in a fixed order, so the output is deterministic.
+type JSONSchema struct {
+	Schema      string `json:"$schema,omitempty"`
+	Ref         string `json:"$ref,omitempty"`
+	Comment     string `json:"$comment,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	// Type is a string, or a list of strings for nullable values, e.g. ["array", "null"]
+	Type            interface{}   `json:"type,omitempty"`
+	Const           interface{}   `json:"const,omitempty"`
+	AnyOf           []*JSONSchema `json:"anyOf,omitempty"`
+	Minimum         *int64        `json:"minimum,omitempty"`
+	Maximum         *uint64       `json:"maximum,omitempty"`
+	ContentEncoding string        `json:"contentEncoding,omitempty"`
+
+	Items                *JSONSchema           `json:"items,omitempty"`
+	Properties           *JSONSchemaProperties `json:"properties,omitempty"`
+	Required             []string              `json:"required,omitempty"`
+	AdditionalProperties *JSONSchema           `json:"additionalProperties,omitempty"`
+	PropertyNames        *JSONSchema           `json:"propertyNames,omitempty"`
+	Pattern              string                `json:"pattern,omitempty"`
+
+	Defs map[string]*JSONSchema `json:"$defs,omitempty"`
+}
+
+// JSONSchemaProperty is a property of an object schema.
+type JSONSchemaProperty struct {
+	Name   string
+	Schema *JSONSchema
+}
+
+// JSONSchemaProperties holds the properties of an object schema, they are encoded in the order of the struct fields or parameters.
+type JSONSchemaProperties []JSONSchemaProperty
+
+// MarshalJSON encodes the properties as object, in order.
+func (ps JSONSchemaProperties) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, p := range ps {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		name, err := json.Marshal(p.Name)
+		if err != nil {
+			return nil, err
+		}
+		schema, err := json.Marshal(p.Schema)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(name)
+		b.WriteByte(':')
+		b.Write(schema)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// JSONSchemaGen creates schemas for the JSON encoding of ango types, as produced by the generated Go code.
+// References to named types are RefPrefix followed by the type name, e.g. "#/$defs/foo".
+type JSONSchemaGen struct {
+	RefPrefix string
+}
+
+// TypeDefinition returns the schema for the definition of t, the types it is defined with are referenced.
+// For a named type, the doc comment is the description.
+func (g *JSONSchemaGen) TypeDefinition(t *Type) *JSONSchema {
+	var s *JSONSchema
+	switch t.Category {
+	case Builtin:
+		s = jsonSchemaBuiltin(t)
+	case Simple:
+		s = g.TypeRef(t.SimpleType)
+	case Slice:
+		if t.SliceElementType.underlying() == TypeUint8 {
+			// like encoding/json, a []uint8 is a base64 string
+			s = &JSONSchema{Type: []string{"string", "null"}, ContentEncoding: "base64"}
+			break
+		}
+		// nil slices are encoded as null
+		s = &JSONSchema{Type: []string{"array", "null"}, Items: g.TypeRef(t.SliceElementType)}
+	case Map:
+		// nil maps are encoded as null, integer keys are encoded as string
+		s = &JSONSchema{Type: []string{"object", "null"}, AdditionalProperties: g.TypeRef(t.MapValueType)}
+		switch key := t.MapKeyType.underlying(); {
+		case key.isJSONInt():
+			s.PropertyNames = &JSONSchema{Pattern: `^-?[0-9]+$`}
+		case key.isJSONUint():
+			s.PropertyNames = &JSONSchema{Pattern: `^[0-9]+$`}
+		}
+	case Struct:
+		// struct fields are named like the Go field, all fields are always encoded
+		props := JSONSchemaProperties{}
+		required := []string{}
+		for _, f := range t.StructFields {
+			name := strings.ToUpper(f.Name[:1]) + f.Name[1:]
+			field := g.TypeRef(f.Type)
+			if f.Doc != "" {
+				field = withDescription(field, f.Doc)
+			}
+			props = append(props, JSONSchemaProperty{Name: name, Schema: field})
+			required = append(required, name)
+		}
+		s = &JSONSchema{Type: "object", Properties: &props, Required: required}
+	default:
+		panic("unknown type category")
+	}
+	if t.Name != "" && t.Category != Builtin {
+		s = withDescription(s, t.Doc)
+		s.Title = t.Name
+	}
+	return s
+}
+
+// TypeRef returns a reference to t, or the definition when t is anonymous or builtin.
+func (g *JSONSchemaGen) TypeRef(t *Type) *JSONSchema {
+	if t.Category == Builtin || t.Name == "" {
+		return g.TypeDefinition(t)
+	}
+	return &JSONSchema{Ref: g.RefPrefix + t.Name}
+}
+
+// Params returns the schema for the data of a message holding params, e.g. the arguments in a "req" message.
+// A param with a struct type is a pointer in Go, it can be null.
+func (g *JSONSchemaGen) Params(params Params) *JSONSchema {
+	props := JSONSchemaProperties{}
+	required := []string{}
+	for _, p := range params {
+		s := g.TypeRef(p.Type)
+		if p.Type.Category == Struct {
+			s = &JSONSchema{AnyOf: []*JSONSchema{s, {Type: "null"}}}
+		}
+		props = append(props, JSONSchemaProperty{Name: p.Name, Schema: s})
+		required = append(required, p.Name)
+	}
+	return &JSONSchema{Type: "object", Properties: &props, Required: required}
+}
+
+// jsonSchemaBuiltin returns the schema for a builtin type, integers have the range of the type as minimum and maximum.
+func jsonSchemaBuiltin(t *Type) *JSONSchema {
+	switch {
+	case t == TypeString:
+		return &JSONSchema{Type: "string"}
+	case t == TypeBool:
+		return &JSONSchema{Type: "boolean"}
+	case t == TypeFloat32 || t == TypeFloat64:
+		return &JSONSchema{Type: "number"}
+	}
+	p := Param{Type: t}
+	min, err := p.NumberMin()
+	if err != nil {
+		panic(fmt.Sprintf("unknown builtin type %s", t.Name))
+	}
+	max, err := p.NumberMax()
+	if err != nil {
+		panic(fmt.Sprintf("unknown builtin type %s", t.Name))
+	}
+	return &JSONSchema{Type: "integer", Minimum: &min, Maximum: &max}
+}
+
+// withDescription returns s with description. A reference gets wrapped, so the referenced schema isn't changed.
+func withDescription(s *JSONSchema, description string) *JSONSchema {
+	if description == "" {
+		return s
+	}
+	if s.Ref != "" {
+		s = &JSONSchema{Ref: s.Ref}
+	}
+	s.Description = description
+	return s
+}
+
+// JSONSchema returns a JSON Schema (draft 2020-12) document for the service.
+// It defines every type in $defs, and the data of the messages for every procedure:
+// "server.add.request" for the arguments, "server.add.response" for the return values.
+// For stream and subscribe procedures the response is the data of every "item" message.
+func (s *Service) JSONSchema() *JSONSchema {
+	g := &JSONSchemaGen{RefPrefix: "#/$defs/"}
+	doc := &JSONSchema{
+		Schema:      JSONSchemaDraft,
+		Title:       s.Name,
+		Description: s.Doc,
+		Comment:     fmt.Sprintf("generated by ango for protocol version %s", s.Version()),
+		Defs:        make(map[string]*JSONSchema),
+	}
+	for name, t := range s.Types {
+		if t.Category != Builtin {
+			doc.Defs[name] = g.TypeDefinition(t)
+		}
+	}
+	for _, side := range []string{"server", "client"} {
+		procs := s.ServerProcedures
+		if side == "client" {
+			procs = s.ClientProcedures
+		}
+		for _, p := range sortedProcedures(procs) {
+			for _, m := range p.JSONSchemaMessages(g) {
+				doc.Defs[side+"."+p.Name+"."+m.Name] = m.Schema
+			}
+		}
+	}
+	return doc
+}
+
+// JSONSchemaMessages returns the schemas for the data of the messages of the procedure:
+// "request" for the arguments, and "response" for the return values when the procedure isn't oneway.
+func (p *Procedure) JSONSchemaMessages(g *JSONSchemaGen) []JSONSchemaProperty {
+	request := g.Params(p.Args)
+	request.Description = fmt.Sprintf("Arguments for %s, the data of the \"req\" message.", p.Name)
+	if p.Doc != "" {
+		request.Description += "\n\n" + p.Doc
+	}
+	messages := []JSONSchemaProperty{{Name: "request", Schema: request}}
+	if p.Oneway {
+		return messages
+	}
+	response := g.Params(p.Rets)
+	switch {
+	case p.Stream:
+		response.Description = fmt.Sprintf("Return values for %s, the data of every \"item\" message on the stream.", p.Name)
+	case p.Subscribe:
+		response.Description = fmt.Sprintf("Return values for %s, the data of every \"item\" message with a new value.", p.Name)
+	default:
+		response.Description = fmt.Sprintf("Return values for %s, the data of the \"res\" message.", p.Name)
+	}
+	return append(messages, JSONSchemaProperty{Name: "response", Schema: response})
+}
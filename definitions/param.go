@@ -29,20 +29,10 @@ func (p *Param) GoTypeName() string {
 	}
 }
 
-// TODO: move method to (t *Type)
-// JsTypeName returns the Js TypeName for the Type
-func (p *Param) JsTypeName() string {
-	switch p.Type.Name {
-	case TypeString.Name:
-		return "string"
-	case TypeBool.Name:
-		return "boolean"
-	case TypeInt.Name, TypeInt8.Name, TypeInt16.Name, TypeInt32.Name, TypeInt64.Name,
-		TypeUint.Name, TypeUint8.Name, TypeUint16.Name, TypeUint32.Name, TypeUint64.Name:
-		return "number"
-	default:
-		return "custom" + p.Type.CapitalizedName()
-	}
+// TsTypeName returns the TypeScript type for the param
+// Used by ango-service.tmpl.ts
+func (p *Param) TsTypeName() string {
+	return tsType(p.Type)
 }
 
 // TODO: move method to (t *Type)
@@ -127,3 +117,12 @@ func (ps Params) GoParameterList() string {
 	}
 	return strings.Join(params, ", ")
 }
+
+// TsParameterList returns the params as TypeScript parameter list, e.g. "a: number, b: string"
+func (ps Params) TsParameterList() string {
+	params := make([]string, 0, len(ps))
+	for _, p := range ps {
+		params = append(params, p.Name+": "+p.TsTypeName())
+	}
+	return strings.Join(params, ", ")
+}
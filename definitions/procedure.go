@@ -15,12 +15,32 @@ var (
 
 // Procedure defines a remote method/function
 type Procedure struct {
-	Type   ProcedureType
-	Oneway bool
-	Name   string
-	Args   Params
-	Rets   Params
-	Source Source
+	Type      ProcedureType
+	Oneway    bool
+	Stream    bool
+	Subscribe bool
+	Name      string
+	Args      Params
+	Rets      Params
+	Source    Source
+
+	// Doc is the comment above the procedure definition, or at the end of the line
+	Doc string
+}
+
+// Kind returns the kind of procedure as written in the .ango file: "oneway", "stream" or "subscribe",
+// or "call" for a procedure that returns once.
+func (p *Procedure) Kind() string {
+	switch {
+	case p.Oneway:
+		return "oneway"
+	case p.Stream:
+		return "stream"
+	case p.Subscribe:
+		return "subscribe"
+	default:
+		return "call"
+	}
 }
 
 // CapitalizedName returns the name, capitalized.
@@ -35,12 +55,73 @@ func (p *Procedure) JsArgs() string {
 	return p.Args.JsParameterList()
 }
 
+// TsArgs returns the TypeScript function definition parameter list
+// Used by ango-service.tmpl.ts
+func (p *Procedure) TsArgs() string {
+	return p.Args.TsParameterList()
+}
+
+// TsArgsData returns the object literal holding the arguments, as sent in the request, e.g. "{ a: a, b: b }"
+// Used by ango-service.tmpl.ts
+func (p *Procedure) TsArgsData() string {
+	fields := make([]string, 0, len(p.Args))
+	for _, param := range p.Args {
+		fields = append(fields, param.Name+": "+param.Name)
+	}
+	if len(fields) == 0 {
+		return "{}"
+	}
+	return "{ " + strings.Join(fields, ", ") + " }"
+}
+
+// TsRetsName returns the name of the TypeScript interface holding the return values.
+// Items of stream and subscribe procedures are named like the Go client's, e.g. TailItem. Other procedures return e.g. AddResult.
+// Used by ango-service.tmpl.ts
+func (p *Procedure) TsRetsName() string {
+	if p.Stream || p.Subscribe {
+		return p.CapitalizedName() + "Item"
+	}
+	return p.CapitalizedName() + "Result"
+}
+
+// TsSubscribeValue returns the TypeScript type for the value of a subscription.
+// Like the Javascript client, a procedure with a single return value uses that value, otherwise the item holding all values.
+// Used by ango-service.tmpl.ts
+func (p *Procedure) TsSubscribeValue() string {
+	if len(p.Rets) == 1 {
+		return p.Rets[0].TsTypeName()
+	}
+	return p.TsRetsName()
+}
+
 // GoArgs returns the go function definition argument ParameterList
 // Used by ango-service.tmpl.go
 func (p *Procedure) GoArgs() string {
 	return p.Args.GoParameterList()
 }
 
+// GoStreamArgs returns the go function definition argument ParameterList for a stream procedure,
+// which has the stream sender as last argument.
+// Used by ango-service.tmpl.go
+func (p *Procedure) GoStreamArgs() string {
+	argStr := p.Args.GoParameterList()
+	if len(argStr) > 0 {
+		argStr += ", "
+	}
+	return argStr + "stream *" + p.CapitalizedName() + "Stream"
+}
+
+// GoSubscribeArgs returns the go function definition argument ParameterList for a subscribe procedure,
+// which has the subscription as last argument.
+// Used by ango-service.tmpl.go
+func (p *Procedure) GoSubscribeArgs() string {
+	argStr := p.Args.GoParameterList()
+	if len(argStr) > 0 {
+		argStr += ", "
+	}
+	return argStr + "sub *" + p.CapitalizedName() + "Subscription"
+}
+
 // GoRets returns the go function definition return ParameterList
 // Used by ango-service.tmpl.go
 func (p *Procedure) GoRets() string {
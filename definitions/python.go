@@ -0,0 +1,284 @@
+package definitions
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The methods in this file generate the declarations and type annotations for the Python client (ango-client.tmpl.py).
+// Structs become dataclasses and other types become aliases for typing annotations, e.g. `SliceFoo = List["Foo"]`.
+// Named types are referenced by a quoted name, so declarations can reference types that are declared later.
+// Quoted names are resolved when values are converted, by _from_json in the generated code.
+// Anonymous structs become dataclasses as well, named after the type and field they are defined in, e.g. FooStr.
+
+// pyKeywords holds the Python keywords and builtin names that can't be used as parameter or field name.
+var pyKeywords = map[string]bool{
+	"False": true, "None": true, "True": true, "and": true, "as": true, "assert": true, "async": true, "await": true,
+	"break": true, "class": true, "continue": true, "def": true, "del": true, "elif": true, "else": true, "except": true,
+	"finally": true, "for": true, "from": true, "global": true, "if": true, "import": true, "in": true, "is": true,
+	"lambda": true, "nonlocal": true, "not": true, "or": true, "pass": true, "raise": true, "return": true, "try": true,
+	"while": true, "with": true, "yield": true, "self": true, "meta": true, "options": true, "stream": true,
+}
+
+// pyIdent returns name as Python identifier, names that are a keyword get an underscore appended.
+func pyIdent(name string) string {
+	if pyKeywords[name] {
+		return name + "_"
+	}
+	return name
+}
+
+// pyGen generates Python declarations, it holds the names given to anonymous structs.
+type pyGen struct {
+	b         strings.Builder
+	anonymous map[*Type]string
+	// pending holds the anonymous structs that were named but not declared yet
+	pending []*Type
+}
+
+// PyName returns the Python identifier for this type: int, float, str or bool for builtin types, other types are capitalized.
+// Used by ango-client.tmpl.py
+func (t *Type) PyName() string {
+	switch t.Category {
+	case Builtin:
+		switch {
+		case t == TypeString:
+			return "str"
+		case t == TypeBool:
+			return "bool"
+		case t == TypeFloat32 || t == TypeFloat64:
+			return "float"
+		default:
+			return "int"
+		}
+	default:
+		return t.CapitalizedName()
+	}
+}
+
+// pyType returns the annotation for a value of type t: the name for builtin types, the quoted name for named types,
+// and the definition for anonymous types.
+func (g *pyGen) pyType(t *Type) string {
+	if t.Category == Builtin {
+		return t.PyName()
+	}
+	if t.Name != "" {
+		return `"` + t.PyName() + `"`
+	}
+	return g.definition(t, "")
+}
+
+// definition returns the annotation for the JSON encoding of values of type t.
+// Like encoding/json, slices of uint8 are base64 strings, they are bytes in Python.
+// Anonymous structs are named after path, e.g. the type and field they are defined in.
+func (g *pyGen) definition(t *Type, path string) string {
+	switch t.Category {
+	case Builtin:
+		return t.PyName()
+	case Simple:
+		return g.pyType(t.SimpleType)
+	case Slice:
+		if t.SliceElementType.underlying() == TypeUint8 {
+			return "bytes"
+		}
+		return "List[" + g.nested(t.SliceElementType, path+"Item") + "]"
+	case Map:
+		return "Dict[" + g.nested(t.MapKeyType, path+"Key") + ", " + g.nested(t.MapValueType, path+"Value") + "]"
+	case Struct:
+		name, ok := g.anonymous[t]
+		if !ok {
+			if path == "" || g.anonymous == nil {
+				// anonymous struct outside a type declaration, e.g. in a procedure parameter
+				return "Dict[str, Any]"
+			}
+			name = path
+			g.anonymous[t] = name
+			g.pending = append(g.pending, t)
+		}
+		return `"` + name + `"`
+	default:
+		panic("unknown type")
+	}
+}
+
+// nested returns the annotation for a type within a definition, anonymous structs within it are named after path.
+func (g *pyGen) nested(t *Type, path string) string {
+	if t.Category == Builtin || t.Name != "" {
+		return g.pyType(t)
+	}
+	return g.definition(t, path)
+}
+
+// dataclass writes the dataclass for a struct type, the fields have the Go field name as JSON name.
+func (g *pyGen) dataclass(name string, t *Type) {
+	g.b.WriteString("@dataclass\nclass " + name + ":\n")
+	if len(t.StructFields) == 0 {
+		g.b.WriteString("    pass\n")
+	}
+	for _, f := range t.StructFields {
+		fieldPath := name + strings.ToUpper(f.Name[:1]) + f.Name[1:]
+		annotation := g.nested(f.Type, fieldPath)
+		jsonName := strings.ToUpper(f.Name[:1]) + f.Name[1:]
+		fmt.Fprintf(&g.b, "    %s: %s = field(%s, metadata={\"json\": %q})\n", pyIdent(f.Name), annotation, pyDefault(f.Type), jsonName)
+	}
+}
+
+// pyDefault returns the default argument for a dataclass field of type t, the Go zero value.
+func pyDefault(t *Type) string {
+	u := t.underlying()
+	switch u.Category {
+	case Builtin:
+		switch u.PyName() {
+		case "str":
+			return `default=""`
+		case "bool":
+			return "default=False"
+		case "float":
+			return "default=0.0"
+		default:
+			return "default=0"
+		}
+	case Slice:
+		if u.SliceElementType.underlying() == TypeUint8 {
+			return `default=b""`
+		}
+		return "default_factory=list"
+	case Map:
+		return "default_factory=dict"
+	default:
+		return "default=None"
+	}
+}
+
+// PyDeclarations returns the Python declarations for all types, sorted by name.
+// Types that are another named type, e.g. `type alias node`, are declared last, after the type they refer to.
+// That way they can be used like that type, e.g. Alias(name="x").
+// Used by ango-client.tmpl.py
+func (s *Service) PyDeclarations() []string {
+	g := &pyGen{anonymous: make(map[*Type]string)}
+	names := make([]string, 0, len(s.Types))
+	for name, t := range s.Types {
+		if t.Category != Builtin {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var decls []string
+	var aliases []*Type
+	for _, name := range names {
+		t := s.Types[name]
+		g.b.Reset()
+		switch {
+		case t.Category == Struct:
+			g.dataclass(t.PyName(), t)
+		case t.Category == Simple && t.SimpleType.Category != Builtin:
+			aliases = append(aliases, t)
+			continue
+		default:
+			fmt.Fprintf(&g.b, "%s = %s\n", t.PyName(), g.definition(t, t.PyName()))
+		}
+		// anonymous structs named while writing this declaration, and the ones within those
+		for len(g.pending) > 0 {
+			a := g.pending[0]
+			g.pending = g.pending[1:]
+			g.b.WriteString("\n\n")
+			g.dataclass(g.anonymous[a], a)
+		}
+		decls = append(decls, g.b.String())
+	}
+
+	// aliases of aliases come after the alias they refer to
+	sort.SliceStable(aliases, func(i, j int) bool { return pyAliasDepth(aliases[i]) < pyAliasDepth(aliases[j]) })
+	for _, t := range aliases {
+		decls = append(decls, t.PyName()+" = "+t.SimpleType.PyName()+"\n")
+	}
+	return decls
+}
+
+// pyAliasDepth returns the number of simple types before the type that defines the value.
+func pyAliasDepth(t *Type) int {
+	depth := 0
+	for t.Category == Simple {
+		t = t.SimpleType
+		depth++
+	}
+	return depth
+}
+
+// PyTypeName returns the Python annotation for the param.
+// Used by ango-client.tmpl.py
+func (p *Param) PyTypeName() string {
+	g := &pyGen{}
+	return g.pyType(p.Type)
+}
+
+// PyName returns the name of the param as Python identifier.
+// Used by ango-client.tmpl.py
+func (p *Param) PyName() string {
+	return pyIdent(p.Name)
+}
+
+// PyDefault returns the default argument for a dataclass field holding the param.
+// Used by ango-client.tmpl.py
+func (p *Param) PyDefault() string {
+	if p.Type.underlying().Category == Struct {
+		return "default=None"
+	}
+	return pyDefault(p.Type)
+}
+
+// PyArgs returns the Python parameter list for the procedure arguments, with a leading comma when not empty, e.g. ", a: int, b: int"
+// Used by ango-client.tmpl.py
+func (p *Procedure) PyArgs() string {
+	s := ""
+	for _, param := range p.Args {
+		s += ", " + param.PyName() + ": " + param.PyTypeName()
+	}
+	return s
+}
+
+// PyArgsData returns the dict literal holding the arguments of the request, e.g. `{"a": a}`, they are converted when the message is encoded.
+// Used by ango-client.tmpl.py
+func (p *Procedure) PyArgsData() string {
+	fields := make([]string, 0, len(p.Args))
+	for _, param := range p.Args {
+		fields = append(fields, fmt.Sprintf("%q: %s", param.Name, param.PyName()))
+	}
+	return "{" + strings.Join(fields, ", ") + "}"
+}
+
+// PyCallArgs returns the arguments for a call to a handler method, decoded from the request data.
+// Used by ango-client.tmpl.py
+func (p *Procedure) PyCallArgs() string {
+	s := ""
+	for _, param := range p.Args {
+		s += fmt.Sprintf("_from_json(%s, data.get(%q)), ", param.PyTypeName(), param.Name)
+	}
+	return s
+}
+
+// PyRetsName returns the name of the dataclass holding the return values, named like the TypeScript interface.
+// Used by ango-client.tmpl.py
+func (p *Procedure) PyRetsName() string {
+	return p.TsRetsName()
+}
+
+// PySubscribeValue returns the annotation for the value of a subscription, the single return value or the item holding all values.
+// Used by ango-client.tmpl.py
+func (p *Procedure) PySubscribeValue() string {
+	if len(p.Rets) == 1 {
+		return p.Rets[0].PyTypeName()
+	}
+	return `"` + p.PyRetsName() + `"`
+}
+
+// PyProcedures returns the procedures sorted by name, Python declarations are written in a fixed order.
+// Used by ango-client.tmpl.py
+func (s *Service) PyProcedures(side string) []*Procedure {
+	if side == "client" {
+		return sortedProcedures(s.ClientProcedures)
+	}
+	return sortedProcedures(s.ServerProcedures)
+}
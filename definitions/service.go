@@ -1,6 +1,7 @@
 package definitions
 
 import (
+	"sort"
 	"strings"
 )
 
@@ -9,6 +10,9 @@ type Service struct {
 	// Name is the name given to the service
 	Name string
 
+	// Doc is the comment above the name clause
+	Doc string
+
 	// Types defined on the service
 	Types map[string]*Type
 
@@ -58,6 +62,22 @@ func (s *Service) JsClientProceduresStringAry() string {
 	return strings.Join(strs, ", ")
 }
 
+// NamedTypes returns the types declared in the .ango file, sorted by name.
+func (s *Service) NamedTypes() []*Type {
+	names := make([]string, 0, len(s.Types))
+	for name, t := range s.Types {
+		if t.Category != Builtin {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	types := make([]*Type, 0, len(names))
+	for _, name := range names {
+		types = append(types, s.Types[name])
+	}
+	return types
+}
+
 // LookupType searches for a type in the service.Types map or BuiltinTypes map.
 // When a type is builtin and is not in service.Types yet, it is added.
 // When a type cannot be found, nil is returned.
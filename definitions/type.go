@@ -29,6 +29,9 @@ const (
 type StructField struct {
 	Name string
 	Type *Type
+
+	// Doc is the comment above the field, or at the end of the line
+	Doc string
 }
 
 // Type is the type of a parameter
@@ -52,6 +55,13 @@ type Type struct {
 
 	// StructFields holds the struct field definitions, only used when Category is Struct.
 	StructFields []StructField
+
+	// Doc is the comment above the type definition, or at the end of the line.
+	// Only set for named types.
+	Doc string
+
+	// Source is where the type was defined, only set for named types.
+	Source Source
 }
 
 // CapitalizedName returns the name, capitalized
@@ -73,6 +83,35 @@ func (t *Type) GoName() string {
 	return t.CapitalizedName()
 }
 
+// GoType returns the Go type for a value of this type: the identifier for builtin and named types,
+// the type definition for anonymous types.
+func (t *Type) GoType() string {
+	return goType(t)
+}
+
+// Underlying returns the type that defines the value, following simple types.
+func (t *Type) Underlying() *Type {
+	return t.underlying()
+}
+
+// JsTypeOf returns the result of the Javascript typeof operator for a JSON decoded value of this type.
+// Slices of uint8 are base64 strings, like with encoding/json.
+func (t *Type) JsTypeOf() string {
+	u := t.underlying()
+	switch {
+	case u == TypeString:
+		return "string"
+	case u == TypeBool:
+		return "boolean"
+	case u.Category == Builtin:
+		return "number"
+	case u.Category == Slice && u.SliceElementType.underlying() == TypeUint8:
+		return "string"
+	default:
+		return "object"
+	}
+}
+
 func (t *Type) GoTypeDefinition() string {
 	switch t.Category {
 	case Builtin:
@@ -107,20 +146,96 @@ var (
 	TypeUint16   = &Type{Name: "uint16", Category: Builtin}
 	TypeUint32   = &Type{Name: "uint32", Category: Builtin}
 	TypeUint64   = &Type{Name: "uint64", Category: Builtin}
+	TypeFloat32  = &Type{Name: "float32", Category: Builtin}
+	TypeFloat64  = &Type{Name: "float64", Category: Builtin}
 	TypeString   = &Type{Name: "string", Category: Builtin}
 	TypeBool     = &Type{Name: "bool", Category: Builtin}
 	BuiltinTypes = map[string]*Type{
-		TypeInt.Name:    TypeInt,
-		TypeInt8.Name:   TypeInt8,
-		TypeInt16.Name:  TypeInt16,
-		TypeInt32.Name:  TypeInt32,
-		TypeInt64.Name:  TypeInt64,
-		TypeUint.Name:   TypeUint,
-		TypeUint8.Name:  TypeUint8,
-		TypeUint16.Name: TypeUint16,
-		TypeUint32.Name: TypeUint32,
-		TypeUint64.Name: TypeUint64,
-		TypeString.Name: TypeString,
-		TypeBool.Name:   TypeBool,
+		TypeInt.Name:     TypeInt,
+		TypeInt8.Name:    TypeInt8,
+		TypeInt16.Name:   TypeInt16,
+		TypeInt32.Name:   TypeInt32,
+		TypeInt64.Name:   TypeInt64,
+		TypeUint.Name:    TypeUint,
+		TypeUint8.Name:   TypeUint8,
+		TypeUint16.Name:  TypeUint16,
+		TypeUint32.Name:  TypeUint32,
+		TypeUint64.Name:  TypeUint64,
+		TypeFloat32.Name: TypeFloat32,
+		TypeFloat64.Name: TypeFloat64,
+		TypeString.Name:  TypeString,
+		TypeBool.Name:    TypeBool,
 	}
 )
+
+// TsName returns the TypeScript identifier for this type.
+// Builtin types are number, string or boolean, other types are capitalized.
+// Used by ango-service.tmpl.ts
+func (t *Type) TsName() string {
+	switch t.Category {
+	case Builtin:
+		switch t {
+		case TypeString:
+			return "string"
+		case TypeBool:
+			return "boolean"
+		default:
+			return "number"
+		}
+	default:
+		return t.CapitalizedName()
+	}
+}
+
+// TsTypeDefinition returns the TypeScript type for the JSON encoding of values of this type.
+// Like encoding/json, slices of uint8 are base64 strings, nil slices and maps are null and struct fields are named like the Go field.
+func (t *Type) TsTypeDefinition() string {
+	switch t.Category {
+	case Builtin:
+		return t.TsName()
+	case Simple:
+		return tsType(t.SimpleType)
+	case Slice:
+		if t.SliceElementType.underlying() == TypeUint8 {
+			return "string | null"
+		}
+		return tsType(t.SliceElementType) + "[] | null"
+	case Map:
+		key := "string"
+		if k := t.MapKeyType.underlying(); k.isJSONInt() || k.isJSONUint() {
+			key = "number"
+		}
+		return "{ [key: " + key + "]: " + tsType(t.MapValueType) + " } | null"
+	case Struct:
+		s := "{ "
+		for _, f := range t.StructFields {
+			s += strings.ToUpper(f.Name[:1]) + f.Name[1:] + ": " + tsType(f.Type) + "; "
+		}
+		s += "}"
+		return s
+	default:
+		panic("unknown type")
+	}
+}
+
+// TsDeclaration returns the exported TypeScript declaration for this type: an interface for structs, a type alias for other types.
+// Used by ango-service.tmpl.ts
+func (t *Type) TsDeclaration() string {
+	if t.Category != Struct {
+		return "export type " + t.TsName() + " = " + t.TsTypeDefinition() + ";"
+	}
+	s := "export interface " + t.TsName() + " {\n"
+	for _, f := range t.StructFields {
+		s += "\t" + strings.ToUpper(f.Name[:1]) + f.Name[1:] + ": " + tsType(f.Type) + ";\n"
+	}
+	s += "}"
+	return s
+}
+
+// tsType returns the TypeScript type for a value of type t: the name for builtin and named types, the definition for anonymous types.
+func tsType(t *Type) string {
+	if t.Category == Builtin || t.Name != "" {
+		return t.TsName()
+	}
+	return t.TsTypeDefinition()
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+)
+
+var docFlags struct {
+	Verbose        bool   `long:"verbose" short:"v" description:"Enable verbose logging"`
+	ForceOverwrite bool   `long:"force-overwrite" description:"Force overwrite (don't ask user)"`
+	InputFile      string `long:"input" short:"i" description:"Input file" required:"true"`
+	OutputFile     string `long:"output" short:"o" description:"Output file, the documentation is written to stdout when not set"`
+	Format         string `long:"format" description:"Output format" choice:"markdown" choice:"html" default:"markdown"`
+	TemplateDir    string `long:"template-dir" description:"Directory with templates that replace the builtin templates with the same file name"`
+}
+
+// runDoc writes the documentation for the service as Markdown or as static HTML page.
+func runDoc(args []string) {
+	parseCommandFlags(&docFlags, args)
+	flags.Verbose = docFlags.Verbose
+	flags.TemplateDir = docFlags.TemplateDir
+
+	templatesBox := findTemplatesBox()
+	tmplName := "ango-doc.tmpl.md"
+	if docFlags.Format == "html" {
+		tmplName = "ango-doc.tmpl.html"
+	}
+	tmpl := loadTemplate(tmplName, templatesBox)
+
+	service := parseInputFile(docFlags.InputFile)
+
+	output := &bytes.Buffer{}
+	err := tmpl.Execute(output, newTemplateData(service))
+	if err != nil {
+		fmt.Printf("Error executing documentation template: %s\n", err)
+		os.Exit(1)
+	}
+
+	err = writeCommandOutput(docFlags.OutputFile, docFlags.ForceOverwrite, output.Bytes())
+	if err != nil {
+		fmt.Printf("Error writing documentation: %s\n", err)
+		os.Exit(1)
+	}
+}
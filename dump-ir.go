@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+var dumpIRFlags struct {
+	Verbose        bool   `long:"verbose" short:"v" description:"Enable verbose logging"`
+	ForceOverwrite bool   `long:"force-overwrite" description:"Force overwrite (don't ask user)"`
+	InputFile      string `long:"input" short:"i" description:"Input file" required:"true"`
+	OutputFile     string `long:"output" short:"o" description:"Output file, the IR is written to stdout when not set"`
+}
+
+// runDumpIR writes the intermediate representation (IR) of the service as JSON, see notes/ir.md.
+func runDumpIR(args []string) {
+	parseCommandFlags(&dumpIRFlags, args)
+	flags.Verbose = dumpIRFlags.Verbose
+
+	service := parseInputFile(dumpIRFlags.InputFile)
+
+	output, err := json.MarshalIndent(service.IR(), "", "\t")
+	if err != nil {
+		fmt.Printf("Error encoding IR: %s\n", err)
+		os.Exit(1)
+	}
+	output = append(output, '\n')
+
+	err = writeCommandOutput(dumpIRFlags.OutputFile, dumpIRFlags.ForceOverwrite, output)
+	if err != nil {
+		fmt.Printf("Error writing IR: %s\n", err)
+		os.Exit(1)
+	}
+}
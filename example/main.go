@@ -64,6 +64,8 @@ func (cs *ChatServiceSession) Notify(text string) {
 	fmt.Printf("instance %d have notification: %s\n", cs.id, text)
 }
 
+var metrics = chatservice.NewMetrics()
+
 //++ TODO: maybe drop ErrorIncommingConnection and have global Debug implementation for debugging. (thoughts.md > hooks),
 //			Errors on incomming connections are not relevant during production runtime. (net/http doesn't expose those errors either..)
 var server = &chatservice.Server{
@@ -71,6 +73,8 @@ var server = &chatservice.Server{
 	ErrorIncommingConnection: func(err error) {
 		fmt.Printf("Error setting up connection: %s\n", err)
 	},
+	Logger:  chatservice.NewStdLogger(nil, chatservice.LogDebug),
+	Metrics: metrics,
 }
 
 func main() {
@@ -82,6 +86,7 @@ func main() {
 
 	http.Handle("/", http.FileServer(httpFiles.HTTPBox()))
 	http.Handle("/websocket-ango-chatservice", server)
+	http.Handle("/metrics", metrics)
 
 	err = http.ListenAndServe(":8123", nil)
 	if err != nil {
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/GeertJohan/ango/definitions"
+)
+
+// generateAsyncAPI writes the AsyncAPI document for the service to the directory given with --asyncapi-path, next to the input file by default.
+func generateAsyncAPI(service *definitions.Service) error {
+	outputDir := outputDirectory(flags.AsyncAPIDir)
+
+	doc, err := json.MarshalIndent(service.AsyncAPI(), "", "\t")
+	if err != nil {
+		return err
+	}
+
+	outputFile, err := createOutputFile(filepath.Join(outputDir, fmt.Sprintf("%s.asyncapi.json", service.Name)), flags.ForceOverwrite)
+	if err != nil {
+		return err
+	}
+	defer outputFile.Close()
+
+	_, err = outputFile.Write(append(doc, '\n'))
+	return err
+}
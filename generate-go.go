@@ -6,45 +6,74 @@ import (
 	"go/format"
 	"os"
 	"path/filepath"
+	"text/template"
 
 	"github.com/GeertJohan/ango/definitions"
-	"github.com/GeertJohan/go.ask"
 )
 
-type dataGo struct {
-	PackageName     string
-	ProtocolVersion string
-	Service         *definitions.Service
-}
-
 func generateGo(service *definitions.Service) error {
 	var err error
+	// the package is written in a directory named after the service, next to the input file by default
 	var outputDir string
-	if filepath.IsAbs(flags.GoDir) {
-		outputDir = flags.GoDir
+	if flags.GoDir == "" {
+		outputDir = filepath.Join(outputDirectory(""), service.Name)
 	} else {
-		wd, err := os.Getwd()
-		if err != nil {
-			fmt.Printf("Error getting PWD: %s\n", err)
-			os.Exit(1)
-		}
-		if flags.GoDir == "" {
-			outputDir = filepath.Join(wd, filepath.Dir(flags.InputFile), service.Name)
-		} else {
-			outputDir = filepath.Join(wd, flags.GoDir)
-		}
+		outputDir = outputDirectory(flags.GoDir)
 	}
 
 	//prepare data
-	data := &dataGo{
-		PackageName:     service.Name,
-		ProtocolVersion: calculateVersion(service),
-		Service:         service,
+	data := newTemplateData(service)
+
+	err = writeGoFile(filepath.Join(outputDir, "server.gen.go"), tmplGo, data)
+	if err != nil {
+		return err
+	}
+	err = writeGoFile(filepath.Join(outputDir, "codec.gen.go"), tmplGoCodec, data)
+	if err != nil {
+		return err
+	}
+	err = writeGoFile(filepath.Join(outputDir, "codec.gen_test.go"), tmplGoCodecTest, data)
+	if err != nil {
+		return err
+	}
+	err = writeGoFile(filepath.Join(outputDir, "client.gen.go"), tmplGoClient, data)
+	if err != nil {
+		return err
 	}
 
+	// fast JSON marshalers and the test comparing them with encoding/json
+	jsonFiles := []struct {
+		name string
+		tmpl *template.Template
+	}{
+		{"json.gen.go", tmplGoJSON},
+		{"json.gen_test.go", tmplGoJSONTest},
+	}
+	for _, f := range jsonFiles {
+		outputFileAbs := filepath.Join(outputDir, f.name)
+		if flags.NoFastJSON {
+			// remove files generated by an earlier run, the types would have methods that are no longer wanted
+			err = os.Remove(outputFileAbs)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		err = writeGoFile(outputFileAbs, f.tmpl, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	// all done
+	return nil
+}
+
+// writeGoFile executes tmpl with data, formats the generated source and writes it to outputFileAbs.
+func writeGoFile(outputFileAbs string, tmpl *template.Template, data *templateData) error {
 	// execute template into buffer
 	generatedSourceBuffer := &bytes.Buffer{}
-	err = tmplGo.Execute(generatedSourceBuffer, data)
+	err := tmpl.Execute(generatedSourceBuffer, data)
 	if err != nil {
 		fmt.Printf("Error executing go template: %s\n", err)
 		os.Exit(1)
@@ -57,33 +86,10 @@ func generateGo(service *definitions.Service) error {
 		formattedSource = generatedSourceBuffer.Bytes()
 	}
 
-	// create outputDir
-	err = os.Mkdir(outputDir, 0755)
-	if err != nil && !os.IsExist(err) {
-		fmt.Printf("Error creating generated package directory: %v", err)
-		os.Exit(1)
-	}
-
 	// create outputFile
-	outputFileName := "server.gen.go"
-	outputFileAbs := filepath.Join(outputDir, outputFileName)
-	var outputFile *os.File
-	outputFile, err = os.OpenFile(outputFileAbs, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+	outputFile, err := createOutputFile(outputFileAbs, flags.ForceOverwrite)
 	if err != nil {
-		if os.IsExist(err) {
-			// output file exists, ask user if we should overwrite.
-			if flags.ForceOverwrite || ask.MustAskf("File '%s' exists, overwrite?", outputFileAbs) {
-				outputFile, err = os.OpenFile(outputFileAbs, os.O_TRUNC|os.O_WRONLY, 0666)
-				if err != nil {
-					return err
-				}
-			} else {
-				fmt.Println("Won't continue.")
-				os.Exit(1)
-			}
-		} else {
-			return err
-		}
+		return err
 	}
 	defer outputFile.Close()
 
@@ -91,7 +97,5 @@ func generateGo(service *definitions.Service) error {
 	if err != nil {
 		fmt.Printf("error writing formatted source to file: %v\n", err)
 	}
-
-	// all done
 	return nil
 }
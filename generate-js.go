@@ -6,63 +6,42 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"text/template"
 
 	"github.com/GeertJohan/ango/definitions"
-	"github.com/GeertJohan/go.ask"
 )
 
-type dataJs struct {
-	ProtocolVersion string
-	Service         *definitions.Service
-}
-
 func generateJs(service *definitions.Service) error {
-	var err error
-	var outputDir string
-	if filepath.IsAbs(flags.JsDir) {
-		outputDir = flags.JsDir
-	} else {
-		wd, err := os.Getwd()
+	outputDir := outputDirectory(flags.JsDir)
+
+	//prepare data
+	data := newTemplateData(service)
+
+	// the AngularJS provider is an adapter over the same client as the ES module and the CommonJS module
+	switch flags.JsTarget {
+	case "module":
+		return writeJsFile(outputDir, fmt.Sprintf("%s.gen.js", service.Name), tmplJsModule, data)
+	case "node":
+		// .mjs is loaded as ES module and .cjs as CommonJS module, regardless of the "type" in package.json
+		err := writeJsFile(outputDir, fmt.Sprintf("%s.gen.mjs", service.Name), tmplJsModule, data)
 		if err != nil {
-			fmt.Printf("Error getting PWD: %s\n", err)
-			os.Exit(1)
-		}
-		if flags.JsDir == "" {
-			outputDir = filepath.Join(wd, filepath.Dir(flags.InputFile))
-		} else {
-			outputDir = filepath.Join(wd, flags.JsDir)
+			return err
 		}
+		return writeJsFile(outputDir, fmt.Sprintf("%s.gen.cjs", service.Name), tmplJsCommonJS, data)
+	default:
+		return writeJsFile(outputDir, fmt.Sprintf("%s.gen.js", service.Name), tmplJs, data)
 	}
+}
 
+// writeJsFile executes tmpl into outputDir/outputFileName, through js-beautify when it is installed.
+func writeJsFile(outputDir string, outputFileName string, tmpl *template.Template, data *templateData) error {
 	// create outputFile
-	outputFileName := fmt.Sprintf("%s.gen.js", service.Name)
-	outputFileAbs := filepath.Join(outputDir, outputFileName)
-	var outputFile *os.File
-	outputFile, err = os.OpenFile(outputFileAbs, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+	outputFile, err := createOutputFile(filepath.Join(outputDir, outputFileName), flags.ForceOverwrite)
 	if err != nil {
-		if os.IsExist(err) {
-			// output file exists, ask user if we should overwrite.
-			if flags.ForceOverwrite || ask.MustAskf("File '%s' exists, overwrite?", filepath.Join(flags.JsDir, outputFileName)) {
-				outputFile, err = os.OpenFile(outputFileAbs, os.O_TRUNC|os.O_WRONLY, 0666)
-				if err != nil {
-					return err
-				}
-			} else {
-				fmt.Println("Won't continue.")
-				os.Exit(1)
-			}
-		} else {
-			return err
-		}
+		return err
 	}
 	defer outputFile.Close()
 
-	//prepare data
-	data := &dataJs{
-		ProtocolVersion: calculateVersion(service),
-		Service:         service,
-	}
-
 	// intermediate io.WriteClose to abstract js-beautify or os.File away from template
 	var outputWriteCloser io.WriteCloser
 
@@ -90,7 +69,7 @@ func generateJs(service *definitions.Service) error {
 	}
 
 	// execute template
-	err = tmplJs.Execute(outputWriteCloser, data)
+	err = tmpl.Execute(outputWriteCloser, data)
 	if err != nil {
 		fmt.Printf("Error executing javascript template: %s\n", err)
 		os.Exit(1)
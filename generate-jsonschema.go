@@ -0,0 +1,28 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/GeertJohan/ango/definitions"
+)
+
+// generateJSONSchema writes the JSON Schema for the service to the directory given with --schema-path, next to the input file by default.
+func generateJSONSchema(service *definitions.Service) error {
+	outputDir := outputDirectory(flags.SchemaDir)
+
+	schema, err := json.MarshalIndent(service.JSONSchema(), "", "\t")
+	if err != nil {
+		return err
+	}
+
+	outputFile, err := createOutputFile(filepath.Join(outputDir, fmt.Sprintf("%s.schema.json", service.Name)), flags.ForceOverwrite)
+	if err != nil {
+		return err
+	}
+	defer outputFile.Close()
+
+	_, err = outputFile.Write(append(schema, '\n'))
+	return err
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/GeertJohan/ango/definitions"
+)
+
+// generatePy writes the Python client to the directory given with --py-path, next to the input file by default.
+func generatePy(service *definitions.Service) error {
+	outputDir := outputDirectory(flags.PyDir)
+
+	// create outputFile, named with an underscore so it can be imported as Python module
+	outputFileName := fmt.Sprintf("%s_gen.py", service.Name)
+	outputFile, err := createOutputFile(filepath.Join(outputDir, outputFileName), flags.ForceOverwrite)
+	if err != nil {
+		return err
+	}
+	defer outputFile.Close()
+
+	//prepare data
+	data := newTemplateData(service)
+
+	// execute template
+	err = tmplPy.Execute(outputFile, data)
+	if err != nil {
+		fmt.Printf("Error executing python template: %s\n", err)
+		os.Exit(1)
+	}
+
+	// all done
+	return nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pyclientServer is the server for testdata/pyclient/pyclient.ango, %s is the import path of the generated package.
+const pyclientServer = `package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"%s"
+)
+
+type session struct {
+	client *pyclient.Client
+}
+
+func (s *session) Stop(err error) {}
+
+func (s *session) Add(a int, b int) (int, error) {
+	if a < 0 {
+		return 0, errors.New("negative")
+	}
+	return a + b, nil
+}
+
+func (s *session) Echo(in *pyclient.Point) (*pyclient.Point, error) {
+	return in, nil
+}
+
+func (s *session) Notify(text string) {
+	// requests are handled in order, waiting for the answer would block the connection
+	go func() {
+		res := <-s.client.Ask(text)
+		if res.Err != nil {
+			s.client.Notified("error: " + res.Err.Error())
+			return
+		}
+		s.client.Notified(res.Answer)
+	}()
+}
+
+func (s *session) Count(n int, stream *pyclient.CountStream) error {
+	if n < 0 {
+		return errors.New("negative count")
+	}
+	for i := 0; i < n; i++ {
+		err := stream.Send(i)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *session) Counter(n int, sub *pyclient.CounterSubscription) error {
+	go func() {
+		for i := 1; i <= n; i++ {
+			if sub.Publish(i) != nil {
+				return
+			}
+		}
+		sub.Close(nil)
+	}()
+	return nil
+}
+
+func main() {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	server := &pyclient.Server{
+		NewSession: func(c *pyclient.Client) pyclient.Session {
+			return &session{client: c}
+		},
+	}
+	http.Handle("/websocket-ango-pyclient", server)
+	fmt.Printf("ws://%%s/websocket-ango-pyclient\n", listener.Addr())
+	http.Serve(listener, nil)
+}
+`
+
+// TestPythonClient generates a Go server and the Python client for testdata/pyclient/pyclient.ango,
+// and runs testdata/pyclient/client.py against the server with both codecs.
+func TestPythonClient(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not found")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go not found")
+	}
+
+	// the server is built within this package's module or GOPATH, so it can import the dependencies of the generated code.
+	// The directory is in .gitignore, in case the test is killed before it is removed.
+	dir, err := ioutil.TempDir(".", "pyclient-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	oldFlags := flags
+	defer func() { flags = oldFlags }()
+	flags.GoDir = filepath.Join(dir, "pyclient")
+	flags.PyDir = dir
+	flags.ForceOverwrite = true
+	setupTemplates()
+	service := parseInputFile("testdata/pyclient/pyclient.ango")
+	err = generateGo(service)
+	if err != nil {
+		t.Fatalf("error generating Go: %v", err)
+	}
+	err = generatePy(service)
+	if err != nil {
+		t.Fatalf("error generating Python: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	pkg := "./" + filepath.ToSlash(flags.GoDir)
+	// only stdout holds the import path, go list may write warnings to stderr
+	importPath, err := exec.CommandContext(ctx, goTool, "list", "-f", "{{.ImportPath}}", pkg).Output()
+	if err != nil {
+		var stderr []byte
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			stderr = exitErr.Stderr
+		}
+		t.Fatalf("error listing generated package: %v\n%s", err, stderr)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte(fmt.Sprintf(pyclientServer, strings.TrimSpace(string(importPath)))), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverBin := filepath.Join(t.TempDir(), "server")
+	out, err := exec.CommandContext(ctx, goTool, "build", "-o", serverBin, "./"+filepath.ToSlash(dir)).CombinedOutput()
+	if err != nil {
+		t.Fatalf("error building server: %v\n%s", err, out)
+	}
+
+	server := exec.CommandContext(ctx, serverBin)
+	server.Stderr = os.Stderr
+	stdout, err := server.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = server.Start()
+	if err != nil {
+		t.Fatalf("error starting server: %v", err)
+	}
+	defer server.Wait()
+	defer server.Process.Kill()
+	url, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("error reading server url: %v", err)
+	}
+
+	pythonPath, err := filepath.Abs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, codec := range []string{"json", "msgpack"} {
+		t.Run(codec, func(t *testing.T) {
+			client := exec.CommandContext(ctx, python, "testdata/pyclient/client.py", strings.TrimSpace(url), codec)
+			client.Env = append(os.Environ(), "PYTHONPATH="+pythonPath)
+			out, err := client.CombinedOutput()
+			if err != nil {
+				t.Fatalf("python client failed: %v\n%s", err, out)
+			}
+		})
+	}
+}
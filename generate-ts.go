@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/GeertJohan/ango/definitions"
+)
+
+// generateTs writes the TypeScript client to the directory given with --ts-path, next to the input file by default.
+func generateTs(service *definitions.Service) error {
+	outputDir := outputDirectory(flags.TsDir)
+
+	// create outputFile
+	outputFileName := fmt.Sprintf("%s.gen.ts", service.Name)
+	outputFile, err := createOutputFile(filepath.Join(outputDir, outputFileName), flags.ForceOverwrite)
+	if err != nil {
+		return err
+	}
+	defer outputFile.Close()
+
+	//prepare data
+	data := newTemplateData(service)
+
+	// execute template
+	err = tmplTs.Execute(outputFile, data)
+	if err != nil {
+		fmt.Printf("Error executing typescript template: %s\n", err)
+		os.Exit(1)
+	}
+
+	// all done
+	return nil
+}
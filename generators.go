@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/GeertJohan/ango/definitions"
+)
+
+// generator writes the generated code for one language or target.
+type generator struct {
+	// name selects the generator with --gen
+	name string
+	// title is used in messages
+	title string
+	// generate writes the output for the service
+	generate func(service *definitions.Service) error
+}
+
+// generators holds all generators, in the order they run by default.
+var generators = []*generator{
+	{name: "js", title: "Javascript", generate: generateJs},
+	{name: "ts", title: "TypeScript", generate: generateTs},
+	{name: "py", title: "Python", generate: generatePy},
+	{name: "go", title: "Go", generate: generateGo},
+	{name: "jsonschema", title: "JSON Schema", generate: generateJSONSchema},
+	{name: "asyncapi", title: "AsyncAPI", generate: generateAsyncAPI},
+}
+
+// lookupGenerator returns the generator with the given name, or nil.
+func lookupGenerator(name string) *generator {
+	for _, g := range generators {
+		if g.name == name {
+			return g
+		}
+	}
+	return nil
+}
+
+// generatorNames returns the names of all generators, comma separated.
+func generatorNames() string {
+	names := make([]string, 0, len(generators))
+	for _, g := range generators {
+		names = append(names, g.name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// selectGenerators returns the generators given with --gen, in the given order.
+// Without --gen, Javascript and Go are generated unless skipped, the other generators when their output path is set.
+func selectGenerators() ([]*generator, error) {
+	if flags.Gen == "" {
+		var selected []*generator
+		if !flags.SkipJs {
+			selected = append(selected, lookupGenerator("js"))
+		}
+		if flags.TsDir != "" {
+			selected = append(selected, lookupGenerator("ts"))
+		}
+		if flags.PyDir != "" {
+			selected = append(selected, lookupGenerator("py"))
+		}
+		if !flags.SkipGo {
+			selected = append(selected, lookupGenerator("go"))
+		}
+		if flags.SchemaDir != "" {
+			selected = append(selected, lookupGenerator("jsonschema"))
+		}
+		if flags.AsyncAPIDir != "" {
+			selected = append(selected, lookupGenerator("asyncapi"))
+		}
+		return selected, nil
+	}
+
+	var selected []*generator
+	for _, name := range strings.Split(flags.Gen, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		g := lookupGenerator(name)
+		if g == nil {
+			return nil, fmt.Errorf("unknown generator '%s', available generators are: %s", name, generatorNames())
+		}
+		selected = append(selected, g)
+	}
+	return selected, nil
+}
+
+// outputDirectory returns the absolute output directory for dir, as given with a --*-path flag.
+// A relative dir is relative to the working directory, an empty dir is the directory of the input file.
+func outputDirectory(dir string) string {
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		fmt.Printf("Error getting PWD: %s\n", err)
+		os.Exit(1)
+	}
+	if dir == "" {
+		return filepath.Join(wd, filepath.Dir(flags.InputFile))
+	}
+	return filepath.Join(wd, dir)
+}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/GeertJohan/ango/definitions"
 	"github.com/GeertJohan/ango/parser"
 	goflags "github.com/jessevdk/go-flags"
 )
@@ -18,8 +19,16 @@ var flags struct {
 	InputFile      string `long:"input" short:"i" description:"Input file" required:"true"`
 	GoDir          string `long:"go-path" description:"Go output directory"`
 	JsDir          string `long:"js-path" description:"Javascript output directory"`
+	JsTarget       string `long:"js-target" description:"Kind of Javascript client: an AngularJS provider, a framework-agnostic ES module, or ES and CommonJS modules for Node.js (.mjs and .cjs)" choice:"angular" choice:"module" choice:"node" default:"angular"`
+	TsDir          string `long:"ts-path" description:"TypeScript output directory, the TypeScript client is only generated when set or selected with --gen"`
+	PyDir          string `long:"py-path" description:"Python output directory, the Python client is only generated when set or selected with --gen"`
+	SchemaDir      string `long:"schema-path" description:"JSON Schema output directory, the JSON Schema is only generated when set or selected with --gen"`
+	AsyncAPIDir    string `long:"asyncapi-path" description:"AsyncAPI output directory, the AsyncAPI document is only generated when set or selected with --gen"`
+	Gen            string `long:"gen" description:"Comma separated list of generators to run (js, ts, py, go, jsonschema, asyncapi), instead of the generators selected by the other flags"`
+	TemplateDir    string `long:"template-dir" description:"Directory with templates that replace the builtin templates with the same file name"`
 	SkipJs         bool   `long:"skip-js" description:"Skip generation of Javascript code"`
 	SkipGo         bool   `long:"skip-go" description:"Skip generation of Go code"`
+	NoFastJSON     bool   `long:"no-fast-json" description:"Don't generate JSON marshalers for Go types, encoding/json uses reflection instead"`
 }
 
 var (
@@ -28,6 +37,14 @@ var (
 )
 
 func main() {
+	// commands are selected by the first argument, e.g. `ango dump-ir -i chatservice.ango`
+	if len(os.Args) > 1 {
+		if c := lookupCommand(os.Args[1]); c != nil {
+			c.run(os.Args[2:])
+			return
+		}
+	}
+
 	verbosef("ango version %s\n", versionFull())
 
 	var err error
@@ -47,16 +64,45 @@ func main() {
 		os.Exit(1)
 	}
 
+	generators, err := selectGenerators()
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		os.Exit(1)
+	}
+
 	setupTemplates()
 
-	inputFile, err := os.Open(flags.InputFile)
+	service := parseInputFile(flags.InputFile)
+
+	protocolVersion := service.Version()
+	verbosef("Calculated protocol version is: %s\n", protocolVersion)
+
+	if len(generators) == 0 {
+		fmt.Printf("Parsed input file successfully.\nNo generators selected.\nGenerated version string was: %s\n", protocolVersion)
+	}
+
+	for _, g := range generators {
+		verbosef("Generating %s.\n", g.title)
+		err = g.generate(service)
+		if err != nil {
+			fmt.Printf("Error generating %s: %s\n", g.title, err)
+			os.Exit(1)
+		}
+	}
+
+	verbosef("ango main() completed\n")
+}
+
+// parseInputFile parses the .ango file at path, it exits when the file can't be parsed.
+func parseInputFile(path string) *definitions.Service {
+	inputFile, err := os.Open(path)
 	if err != nil {
 		fmt.Printf("Error opening input file: %s\n", err)
 		os.Exit(1)
 	}
 	defer inputFile.Close()
 
-	verbosef("Parsing %s.\n", flags.InputFile)
+	verbosef("Parsing %s.\n", path)
 	angoParser := parser.NewParser(&parser.Config{
 		PrintParseErrors: false,
 	})
@@ -65,35 +111,11 @@ func main() {
 		fmt.Printf("Error parsing ango definitions: %s\n", err)
 		os.Exit(1)
 	}
-	if service.Name != strings.TrimSuffix(filepath.Base(flags.InputFile), ".ango") {
+	if service.Name != strings.TrimSuffix(filepath.Base(path), ".ango") {
 		fmt.Println("Warning: .ango filename doesn't match name clause in file.")
 	}
-	verbosef("File %s parsed.\n", flags.InputFile)
-
-	protocolVersion := calculateVersion(service)
-	verbosef("Calculated protocol version is: %s\n", protocolVersion)
-
-	if flags.SkipGo && flags.SkipJs {
-		fmt.Printf("Parsed input file successfully.\nSkipping both Go and Javscript generation.\nGenerated version string was: %s\n", protocolVersion)
-	}
-
-	if !flags.SkipJs {
-		err = generateJs(service)
-		if err != nil {
-			fmt.Printf("Error generating Javascript: %s\n", err)
-			os.Exit(1)
-		}
-	}
-
-	if !flags.SkipGo {
-		err = generateGo(service)
-		if err != nil {
-			fmt.Printf("Error generating Go: %s\n", err)
-			os.Exit(1)
-		}
-	}
-
-	verbosef("ango main() completed\n")
+	verbosef("File %s parsed.\n", path)
+	return service
 }
 
 func verbosef(format string, data ...interface{}) {
@@ -11,7 +11,13 @@ import (
 type lineReader struct {
 	ln      int
 	bufline *string
+	bufdoc  string
 	bufrd   *bufio.Reader
+
+	// doc holds the doc comment for the last line returned by Line
+	doc string
+	// comment holds the comment lines read since the last empty line or statement
+	comment []string
 }
 
 func newLineReader(rd io.Reader) *lineReader {
@@ -20,14 +26,13 @@ func newLineReader(rd io.Reader) *lineReader {
 	}
 }
 
-// Line currently skips empty lines and removes comments.
-// when the parser is more matured it should return all lines,
-// especially the comments (maybe empty lines can still be skipped here)..
-// OR instead: have a method "RelatedComment()" that returns a string containing a comment related to the current line.
+// Line skips empty lines and removes comments.
+// Comments directly above the line, or at the end of the line, are available with Doc.
 func (lr *lineReader) Line() (string, error) {
 	if lr.bufline != nil {
 		line := *lr.bufline
 		lr.bufline = nil
+		lr.doc = lr.bufdoc
 		return line, nil
 	}
 	for {
@@ -37,19 +42,37 @@ func (lr *lineReader) Line() (string, error) {
 			return "", err
 		}
 
-		line = removeComments(line)
+		text, comment := splitComment(line)
 
 		// remove whitespace
-		line = strings.TrimSpace(line)
+		text = strings.TrimSpace(text)
 
-		if len(line) == 0 {
+		if len(text) == 0 {
+			if comment, ok := commentText(line); ok {
+				lr.comment = append(lr.comment, comment)
+			} else {
+				// an empty line separates comments from the statement below
+				lr.comment = nil
+			}
 			continue
 		}
 
-		return line, nil
+		// like in Go, the comment lines directly above a statement are its doc comment.
+		// A statement without them is documented by the comment at the end of the line.
+		lr.doc = strings.Join(lr.comment, "\n")
+		if lr.doc == "" {
+			lr.doc = comment
+		}
+		lr.comment = nil
+		return text, nil
 	}
 }
 
+// Doc returns the doc comment for the last line returned by Line, or an empty string when it has none.
+func (lr *lineReader) Doc() string {
+	return lr.doc
+}
+
 func (lr *lineReader) Peek() (string, error) {
 	if lr.bufline != nil {
 		return *lr.bufline, nil
@@ -59,14 +82,25 @@ func (lr *lineReader) Peek() (string, error) {
 		return "", err
 	}
 	lr.bufline = &line
+	lr.bufdoc = lr.doc
 	return line, nil
 }
 
-func removeComments(line string) string {
+// splitComment returns the line without comment, and the text of the comment.
+func splitComment(line string) (string, string) {
 	cPos := strings.Index(line, "//")
 	if cPos == -1 {
 		// no comment, return complete line
-		return line
+		return line, ""
+	}
+	return line[:cPos], strings.TrimSpace(line[cPos+2:])
+}
+
+// commentText returns the text of a line holding only a comment, and false when the line doesn't hold a comment.
+func commentText(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "//") {
+		return "", false
 	}
-	return line[:cPos]
+	return strings.TrimSpace(line[2:]), true
 }
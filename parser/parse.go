@@ -37,7 +37,7 @@ func init() {
 		// used to capture procedure definition (and partials)
 		rParam            = rOptWhitepsace + rIdentifier + rMustWhitespace + rIdentifier + rOptWhitepsace
 		rParameters       = `\((?:` + rParam + `)?(?:,` + rParam + `)*\)`
-		rProcedureCapture = `^(server|client)` + rMustWhitespace + `(?:(oneway)` + rMustWhitespace + `)?(` + rIdentifier + `)` + rOptWhitepsace + `(` + rParameters + `)` + rOptWhitepsace + `((?:` + rParameters + `)?)` + rOptWhitepsace + `$`
+		rProcedureCapture = `^(server|client)` + rMustWhitespace + `(?:(oneway|stream|subscribe)` + rMustWhitespace + `)?(` + rIdentifier + `)` + rOptWhitepsace + `(` + rParameters + `)` + rOptWhitepsace + `((?:` + rParameters + `)?)` + rOptWhitepsace + `$`
 
 		// used to capture parameters
 		rParamCapture = rOptWhitepsace + `(` + rIdentifier + `)` + rMustWhitespace + `(` + rIdentifier + `)` + rOptWhitepsace
@@ -88,6 +88,24 @@ var (
 	// ParseErrEmptyReturnGroup indicates parenthesis for return values are given, but no actual return parameters inside them.
 	ParseErrEmptyReturnGroup = "empty return group"
 
+	// ParseErrMissingStreamItem indicates a stream procedure was defined without return parameters.
+	// The return parameters define the items sent on the stream.
+	ParseErrMissingStreamItem = "missing return parameters for stream items"
+
+	// ParseErrMissingSubscriptionValue indicates a subscribe procedure was defined without return parameters.
+	// The return parameters define the value that is published to the subscriber.
+	ParseErrMissingSubscriptionValue = "missing return parameters for subscription value"
+
+	// ParseErrClientSubscribe indicates a subscribe procedure was defined for the client.
+	ParseErrClientSubscribe = "subscribe procedures can only be defined for the server"
+
+	// ParseErrSharedProcedureIdentifier indicates a procedure identifier is used for both a server and a client procedure.
+	// The generated Go server and client share a package, their types for the procedures would collide.
+	ParseErrSharedProcedureIdentifier = "procedure identifier used for both server and client"
+
+	// ParseErrReservedProcedureIdentifier indicates a procedure identifier that collides with a method in the generated Go code.
+	ParseErrReservedProcedureIdentifier = "reserved procedure identifier"
+
 	// ParseErrUnexpectedEOF indicates that the parse expected more lines, but got EOF
 	ParseErrUnexpectedEOF = "unexpected EOF"
 
@@ -95,6 +113,22 @@ var (
 	ParseErrReader = "reader error"
 )
 
+// reservedServerProcedureNames maps the capitalized names that server procedures can't have to the generated method using it.
+// Server procedures are methods on Session and Conn.
+var reservedServerProcedureNames = map[string]string{
+	"Stop":      "Session.Stop",
+	"Close":     "Conn.Close",
+	"Done":      "Conn.Done",
+	"Err":       "Conn.Err",
+	"GoingAway": "Conn.GoingAway",
+}
+
+// reservedClientProcedureNames maps the capitalized names that client procedures can't have to the generated method using it.
+// Client procedures are methods on Client and ClientHandler.
+var reservedClientProcedureNames = map[string]string{
+	"ConnInfo": "Client.ConnInfo",
+}
+
 var (
 	// ErrNotImlemented indicates a feature has not been implemented yet.
 	ErrNotImlemented = errors.New("not implemented")
@@ -200,6 +234,7 @@ func (parser *Parser) parseName() *ParseError {
 	}
 
 	parser.service.Name = matches[1]
+	parser.service.Doc = parser.lr.Doc()
 
 	return nil
 }
@@ -215,6 +250,7 @@ func (parser *Parser) parseProcedure() *ParseError {
 		Source: definitions.Source{
 			Linenumber: parser.lr.ln,
 		},
+		Doc: parser.lr.Doc(),
 	}
 	switch matches[1] {
 	case "server":
@@ -229,6 +265,17 @@ func (parser *Parser) parseProcedure() *ParseError {
 	if proc.Oneway && len(matches[5]) > 0 {
 		return parser.newError(ParseErrUnexpectedReturnParameters)
 	}
+	proc.Stream = (matches[2] == "stream")
+	if proc.Stream && len(matches[5]) == 0 {
+		return parser.newError(ParseErrMissingStreamItem)
+	}
+	proc.Subscribe = (matches[2] == "subscribe")
+	if proc.Subscribe && proc.Type != definitions.ServerProcedure {
+		return parser.newError(ParseErrClientSubscribe)
+	}
+	if proc.Subscribe && len(matches[5]) == 0 {
+		return parser.newError(ParseErrMissingSubscriptionValue)
+	}
 
 	proc.Name = matches[3]
 
@@ -244,17 +291,36 @@ func (parser *Parser) parseProcedure() *ParseError {
 		return parser.newError(ParseErrEmptyReturnGroup)
 	}
 
-	var procMap map[string]*definitions.Procedure
+	var procMap, otherProcMap map[string]*definitions.Procedure
+	var reserved map[string]string
+	var otherSide string
 	switch proc.Type {
 	case definitions.ClientProcedure:
 		procMap = parser.service.ClientProcedures
+		otherProcMap = parser.service.ServerProcedures
+		reserved = reservedClientProcedureNames
+		otherSide = "server"
 	case definitions.ServerProcedure:
 		procMap = parser.service.ServerProcedures
+		otherProcMap = parser.service.ClientProcedures
+		reserved = reservedServerProcedureNames
+		otherSide = "client"
 	default:
 		panic("unreachable")
 	}
-	if _, exists := procMap[proc.Name]; exists {
-		return parser.newErrorExtra(ParseErrDuplicateProcedureIdentifier, `"%s"`, proc.Name)
+	if method, isReserved := reserved[proc.CapitalizedName()]; isReserved {
+		return parser.newErrorExtra(ParseErrReservedProcedureIdentifier, `"%s" collides with %s in the generated Go code`, proc.Name, method)
+	}
+	// procedures differing only in the case of the first letter have the same Go name
+	for _, other := range procMap {
+		if other.CapitalizedName() == proc.CapitalizedName() {
+			return parser.newErrorExtra(ParseErrDuplicateProcedureIdentifier, `"%s"`, proc.Name)
+		}
+	}
+	for _, other := range otherProcMap {
+		if other.CapitalizedName() == proc.CapitalizedName() {
+			return parser.newErrorExtra(ParseErrSharedProcedureIdentifier, `"%s" collides with %s procedure "%s" at line %d`, proc.Name, otherSide, other.Name, other.Source.Linenumber)
+		}
 	}
 	procMap[proc.Name] = proc
 
@@ -324,6 +390,10 @@ func (parser *Parser) parseTypeDefinition() *ParseError {
 	}
 	t := &definitions.Type{
 		Name: fields[1],
+		Doc:  parser.lr.Doc(),
+		Source: definitions.Source{
+			Linenumber: parser.lr.ln,
+		},
 	}
 
 	typetext := fields[2:]
@@ -370,6 +440,7 @@ func (parser *Parser) parseType(t *definitions.Type, typetext []string) (*defini
 			//++ TODO: verify that fieldFields[0] is a valid field identifier!
 			sf := definitions.StructField{
 				Name: fieldFields[0],
+				Doc:  parser.lr.Doc(),
 			}
 			var perr *ParseError
 			sf.Type, perr = parser.parseType(nil, fieldFields[1:])
@@ -0,0 +1,104 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GeertJohan/ango/parser"
+)
+
+func TestProcedureIdentifierCollision(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		// errType is the expected ParseError.Type, empty when the source is valid
+		errType string
+		line    int
+	}{
+		{
+			name:   "distinct names",
+			source: "server stream add(a int)(b int)\nserver closeAll()\nclient stop()\nclient done(a int)\n",
+		},
+		{
+			name:    "duplicate server procedure",
+			source:  "server add(a int)(b int)\nserver oneway add(a int)\n",
+			errType: parser.ParseErrDuplicateProcedureIdentifier,
+			line:    3,
+		},
+		{
+			name:    "duplicate with capitalized name",
+			source:  "client add(a int)(b int)\nclient Add(a int)\n",
+			errType: parser.ParseErrDuplicateProcedureIdentifier,
+			line:    3,
+		},
+		{
+			// the generated client would declare AddStream for the client procedure, as the server does for the server procedure
+			name:    "server and client",
+			source:  "server stream add(a int)(b int)\nclient stream add(a int)(b int)\n",
+			errType: parser.ParseErrSharedProcedureIdentifier,
+			line:    3,
+		},
+		{
+			name:    "client and server with capitalized name",
+			source:  "client notify(text string)\nserver oneway Notify(text string)\n",
+			errType: parser.ParseErrSharedProcedureIdentifier,
+			line:    3,
+		},
+		{
+			name:    "server close",
+			source:  "server close()\n",
+			errType: parser.ParseErrReservedProcedureIdentifier,
+			line:    2,
+		},
+		{
+			name:    "server done",
+			source:  "server oneway done()\n",
+			errType: parser.ParseErrReservedProcedureIdentifier,
+			line:    2,
+		},
+		{
+			name:    "server err",
+			source:  "server err()(text string)\n",
+			errType: parser.ParseErrReservedProcedureIdentifier,
+			line:    2,
+		},
+		{
+			name:    "server goingAway",
+			source:  "server subscribe goingAway()(at int)\n",
+			errType: parser.ParseErrReservedProcedureIdentifier,
+			line:    2,
+		},
+		{
+			name:    "server stop",
+			source:  "server Stop()\n",
+			errType: parser.ParseErrReservedProcedureIdentifier,
+			line:    2,
+		},
+		{
+			name:    "client connInfo",
+			source:  "client connInfo()(text string)\n",
+			errType: parser.ParseErrReservedProcedureIdentifier,
+			line:    2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			source := "name collisions\n" + test.source
+			_, err := parser.NewParser(&parser.Config{}).Parse(strings.NewReader(source))
+			if test.errType == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			perr, ok := err.(*parser.ParseError)
+			if !ok {
+				t.Fatalf("expected a *ParseError with type %q, got %v", test.errType, err)
+			}
+			if perr.Type != test.errType || perr.Line != test.line {
+				t.Fatalf("expected %q at line %d, got %v", test.errType, test.line, perr)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"html"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/GeertJohan/ango/definitions"
+)
+
+// templateFuncs are available in all templates, including the templates in --template-dir.
+// They are documented in notes/templates.md, changes must be backwards compatible.
+var templateFuncs = template.FuncMap{
+	// casing
+	"capitalize":   capitalize,
+	"uncapitalize": uncapitalize,
+	"lower":        strings.ToLower,
+	"upper":        strings.ToUpper,
+	"snake":        snakeCase,
+	"join":         strings.Join,
+
+	// types
+	"goType": func(t *definitions.Type) string {
+		return t.GoType()
+	},
+	"tsType": func(t *definitions.Type) string {
+		return (&definitions.Param{Type: t}).TsTypeName()
+	},
+	"pyType": func(t *definitions.Type) string {
+		return (&definitions.Param{Type: t}).PyTypeName()
+	},
+	"underlying": func(t *definitions.Type) *definitions.Type {
+		return t.Underlying()
+	},
+
+	// Javascript type checks
+	"jsTypeOf": func(t *definitions.Type) string {
+		return t.JsTypeOf()
+	},
+	"isNumber": func(t *definitions.Type) bool {
+		return t.JsTypeOf() == "number"
+	},
+	"isInteger": func(t *definitions.Type) bool {
+		return (&definitions.Param{Type: t.Underlying()}).IsNumber()
+	},
+	"numberMin": func(t *definitions.Type) (int64, error) {
+		return definitions.Param{Type: t.Underlying()}.NumberMin()
+	},
+	"numberMax": func(t *definitions.Type) (uint64, error) {
+		return definitions.Param{Type: t.Underlying()}.NumberMax()
+	},
+
+	// documentation, references to named types link to the type, e.g. "#type-foo"
+	"mdType": func(t *definitions.Type) string {
+		return docTypeRef(t, mdLink, mdEscape)
+	},
+	"mdTypeDefinition": func(t *definitions.Type) string {
+		return docTypeDefinition(t, mdLink, mdEscape)
+	},
+	"htmlType": func(t *definitions.Type) string {
+		return docTypeRef(t, htmlLink, html.EscapeString)
+	},
+	"htmlTypeDefinition": func(t *definitions.Type) string {
+		return docTypeDefinition(t, htmlLink, html.EscapeString)
+	},
+	"mdInline": func(s string) string {
+		return strings.NewReplacer("\n", " ", "|", `\|`).Replace(s)
+	},
+	"docProcedure": func(side string, p *definitions.Procedure) *docProcedure {
+		return &docProcedure{Side: side, Procedure: p}
+	},
+}
+
+// docProcedure holds a procedure and the side implementing it ("server" or "client"), for the procedure template in documentation.
+type docProcedure struct {
+	Side      string
+	Procedure *definitions.Procedure
+}
+
+// capitalize returns s with the first letter in upper case.
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// uncapitalize returns s with the first letter in lower case.
+func uncapitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+// snakeCase returns s in snake case, e.g. "sendMessage" becomes "send_message".
+func snakeCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// docTypeRef returns a reference to t in ango syntax, named types are written with link and other text with escape.
+// Anonymous types are written as their definition.
+func docTypeRef(t *definitions.Type, link func(name string) string, escape func(s string) string) string {
+	if t.Category == definitions.Builtin {
+		return escape(t.Name)
+	}
+	if t.Name != "" {
+		return link(t.Name)
+	}
+	return docTypeDefinition(t, link, escape)
+}
+
+// docTypeDefinition returns the definition of t in ango syntax, e.g. "[]foo" for `type foos []foo`.
+func docTypeDefinition(t *definitions.Type, link func(name string) string, escape func(s string) string) string {
+	switch t.Category {
+	case definitions.Builtin:
+		return escape(t.Name)
+	case definitions.Simple:
+		return docTypeRef(t.SimpleType, link, escape)
+	case definitions.Slice:
+		return escape("[]") + docTypeRef(t.SliceElementType, link, escape)
+	case definitions.Map:
+		return escape("map[") + docTypeRef(t.MapKeyType, link, escape) + escape("]") + docTypeRef(t.MapValueType, link, escape)
+	case definitions.Struct:
+		fields := make([]string, 0, len(t.StructFields))
+		for _, f := range t.StructFields {
+			fields = append(fields, escape(f.Name+" ")+docTypeRef(f.Type, link, escape))
+		}
+		return escape("struct{ ") + strings.Join(fields, escape("; ")) + escape(" }")
+	default:
+		panic("unknown type category")
+	}
+}
+
+// mdLink returns a Markdown link to the documentation of the named type.
+func mdLink(name string) string {
+	return "[" + name + "](#type-" + name + ")"
+}
+
+// mdEscape escapes the characters in ango type syntax that have a meaning in Markdown.
+func mdEscape(s string) string {
+	return strings.NewReplacer("[", `\[`, "]", `\]`).Replace(s)
+}
+
+// htmlLink returns a HTML link to the documentation of the named type.
+func htmlLink(name string) string {
+	return `<a href="#type-` + name + `">` + name + `</a>`
+}
@@ -2,41 +2,137 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"text/template"
 
+	"github.com/GeertJohan/ango/definitions"
 	"github.com/GeertJohan/go.rice"
 )
 
+// templateData is the data given to every template, see notes/templates.md.
+// Templates in --template-dir rely on it, so fields may be added but not changed or removed.
+type templateData struct {
+	// PackageName is the name of the generated Go package
+	PackageName string
+	// ProtocolVersion is the version calculated from the wire contract, see Service.Version
+	ProtocolVersion string
+	// SignatureSet lists the procedure signatures, sent during the handshake
+	SignatureSet string
+	// Service holds the parsed definitions
+	Service *definitions.Service
+}
+
+// newTemplateData returns the template data for service.
+func newTemplateData(service *definitions.Service) *templateData {
+	return &templateData{
+		PackageName:     service.Name,
+		ProtocolVersion: service.Version(),
+		SignatureSet:    service.SignatureSet(),
+		Service:         service,
+	}
+}
+
 var (
-	tmplJs *template.Template
-	tmplGo *template.Template
+	tmplJs          *template.Template
+	tmplJsModule    *template.Template
+	tmplJsCommonJS  *template.Template
+	tmplTs          *template.Template
+	tmplPy          *template.Template
+	tmplGo          *template.Template
+	tmplGoCodec     *template.Template
+	tmplGoCodecTest *template.Template
+	tmplGoClient    *template.Template
+	tmplGoJSON      *template.Template
+	tmplGoJSONTest  *template.Template
 )
 
 func setupTemplates() {
+	templatesBox := findTemplatesBox()
 
-	// load rice box
+	tmplJs = loadTemplate("ango-service.tmpl.js", templatesBox, "ango-core.tmpl.js")
+	tmplJsModule = loadTemplate("ango-module.tmpl.js", templatesBox, "ango-core.tmpl.js")
+	tmplJsCommonJS = loadTemplate("ango-commonjs.tmpl.js", templatesBox, "ango-core.tmpl.js")
+	tmplTs = loadTemplate("ango-service.tmpl.ts", templatesBox)
+	tmplPy = loadTemplate("ango-client.tmpl.py", templatesBox)
+	tmplGo = loadTemplate("ango-service.tmpl.go", templatesBox)
+	tmplGoCodec = loadTemplate("ango-codec.tmpl.go", templatesBox)
+	tmplGoCodecTest = loadTemplate("ango-codec-test.tmpl.go", templatesBox)
+	tmplGoClient = loadTemplate("ango-client.tmpl.go", templatesBox)
+	tmplGoJSON = loadTemplate("ango-json.tmpl.go", templatesBox)
+	tmplGoJSONTest = loadTemplate("ango-json-test.tmpl.go", templatesBox)
+}
+
+// findTemplatesBox returns the rice box holding the builtin templates.
+// With --template-dir, it warns about files in the directory that don't replace a builtin template.
+func findTemplatesBox() *rice.Box {
 	templatesBox, err := rice.FindBox("templates")
 	if err != nil {
 		fmt.Printf("Error loading templates: %s\n", err)
 		os.Exit(1)
 	}
 
-	tmplJs = loadTemplate("ango-service.tmpl.js", templatesBox)
-	tmplGo = loadTemplate("ango-service.tmpl.go", templatesBox)
+	if flags.TemplateDir != "" {
+		checkTemplateDir(templatesBox)
+	}
+	return templatesBox
 }
 
-func loadTemplate(name string, templatesBox *rice.Box) *template.Template {
-	str, err := templatesBox.String(name)
+// checkTemplateDir warns about files in --template-dir that don't replace a builtin template,
+// most likely the file name has a typo.
+func checkTemplateDir(templatesBox *rice.Box) {
+	files, err := ioutil.ReadDir(flags.TemplateDir)
 	if err != nil {
-		fmt.Printf("Error getting template '%s': %s\n", name, err)
+		fmt.Printf("Error reading template directory: %s\n", err)
 		os.Exit(1)
 	}
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		if _, err := templatesBox.Bytes(f.Name()); err != nil {
+			fmt.Printf("Warning: '%s' in template directory is not an ango template, it is not used.\n", f.Name())
+		}
+	}
+}
 
-	tmpl, err := template.New(name).Parse(str)
-	if err != nil {
-		fmt.Printf("Error parsing template '%s': %s\n", name, err)
-		os.Exit(1)
+// readTemplate returns the template source from --template-dir when the file exists there, otherwise from the rice box.
+func readTemplate(name string, templatesBox *rice.Box) (string, error) {
+	if flags.TemplateDir != "" {
+		b, err := ioutil.ReadFile(filepath.Join(flags.TemplateDir, name))
+		if err == nil {
+			verbosef("Using template %s from %s\n", name, flags.TemplateDir)
+			return string(b), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	return templatesBox.String(name)
+}
+
+// loadTemplate loads and parses a template. The templates in includes are parsed into the same set,
+// so the template can use them by name, e.g. {{template "ango-core.tmpl.js" .}}.
+func loadTemplate(name string, templatesBox *rice.Box, includes ...string) *template.Template {
+	var tmpl *template.Template
+	for _, n := range append([]string{name}, includes...) {
+		str, err := readTemplate(n, templatesBox)
+		if err != nil {
+			fmt.Printf("Error getting template '%s': %s\n", n, err)
+			os.Exit(1)
+		}
+
+		if tmpl == nil {
+			tmpl = template.New(n).Funcs(templateFuncs)
+		} else {
+			tmpl = tmpl.New(n)
+		}
+		_, err = tmpl.Parse(str)
+		if err != nil {
+			fmt.Printf("Error parsing template '%s': %s\n", n, err)
+			os.Exit(1)
+		}
 	}
-	return tmpl
+	return tmpl.Lookup(name)
 }
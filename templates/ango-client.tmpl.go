@@ -0,0 +1,788 @@
+// WARNING This is generated code by the ango tool (github.com/GeertJohan/ango)
+// DO NOT EDIT unless you know what you're doing!
+// The generated code requires Go 1.21 or later.
+
+package {{.PackageName}}
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GeertJohan/go.incremental"
+	"github.com/gorilla/websocket"
+)
+
+// ErrUnknownCodec indicates Dialer.Codec or the codec selected by the server is not supported.
+var ErrUnknownCodec = errors.New("unknown codec")
+
+// ClientHandler defines all methods that can be called by the server on a connection made with Dial.
+// The methods are called concurrently, each call in its own goroutine.
+type ClientHandler interface {
+	{{range .Service.ClientProcedures}}
+		{{if .Stream}}
+			// {{.CapitalizedName}} is a ango stream procedure defined in the .ango file.
+			// Items are sent using the stream. The stream ends when {{.CapitalizedName}} returns.
+			{{.CapitalizedName}}( {{.GoStreamArgs}} )( err error )
+		{{else}}
+			// {{.CapitalizedName}} is a ango procedure defined in the .ango file
+			{{.CapitalizedName}}( {{.GoArgs}} )( {{.GoRets}} )
+		{{end}}
+	{{end}}
+}
+
+{{range .Service.ClientProcedures}}{{if .Stream}}
+	// {{.CapitalizedName}}Stream sends the items for a call to the client stream procedure {{.Name}}.
+	type {{.CapitalizedName}}Stream struct {
+		ctx    context.Context
+		stream *connStream
+	}
+
+	// Context returns the context for the stream. It is cancelled when the server cancels the stream or the connection closes.
+	func (s *{{.CapitalizedName}}Stream) Context() context.Context {
+		return s.ctx
+	}
+
+	// Send sends an item to the server. Send blocks while the server has not consumed enough of the earlier items (flow control).
+	// Send returns ErrStreamCancelled when the stream was cancelled.
+	func (s *{{.CapitalizedName}}Stream) Send( {{.Rets.GoParameterList}} ) error {
+		return s.stream.send(&angoClientRetsData{{.CapitalizedName}}{
+			{{range .Rets}}
+				{{.CapitalizedName}}: {{.Name}},{{end}}
+		})
+	}
+{{end}}{{end}}
+
+// Dialer holds the options to connect to the service from Go. The zero value is ready to use.
+type Dialer struct {
+	// Codec is the codec asked for during the handshake, CodecJSON or CodecMessagePack.
+	// The server can fall back to JSON. When empty, JSON is used.
+	Codec string
+
+	// Header is sent with the websocket handshake request, e.g. to pass cookies or authorization.
+	Header http.Header
+
+	// Logger is used to log debug information and errors. When nil, nothing is logged.
+	Logger Logger
+
+	// WebsocketDialer opens the websocket. When nil, websocket.DefaultDialer is used.
+	WebsocketDialer *websocket.Dialer
+}
+
+// Dial connects to the service at url, e.g. "ws://localhost:8080/websocket-ango-{{.Service.Name}}", using a zero Dialer.
+// Calls from the server to client procedures are handled by handler, which can be nil when the service has no client procedures.
+func Dial(url string, handler ClientHandler) (*Conn, error) {
+	return (&Dialer{}).Dial(context.Background(), url, handler)
+}
+
+// Dial connects to the service at url and performs the version handshake.
+// ctx limits the time to set up the connection, it doesn't affect the connection after Dial returned.
+// When the protocol version differs, the procedures with the same signature on both sides can still be called.
+// Dial returns ErrIncompatibleVersion when the server rejected the handshake.
+func (d *Dialer) Dial(ctx context.Context, url string, handler ClientHandler) (*Conn, error) {
+	codecOffer := CodecJSON
+	if d.Codec != "" && d.Codec != CodecJSON {
+		if _, ok := codecs[d.Codec]; !ok {
+			return nil, ErrUnknownCodec
+		}
+		codecOffer = d.Codec + "," + CodecJSON
+	}
+
+	wsDialer := d.WebsocketDialer
+	if wsDialer == nil {
+		wsDialer = websocket.DefaultDialer
+	}
+	ws, _, err := wsDialer.DialContext(ctx, url, d.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	// the server answers "good [codec [signatures]]" or "invalid"
+	if deadline, ok := ctx.Deadline(); ok {
+		ws.SetReadDeadline(deadline)
+	}
+	err = ws.WriteMessage(websocket.TextMessage, []byte(ProtocolVersion+" "+codecOffer+" "+angoSignatures))
+	if err != nil {
+		ws.Close()
+		return nil, err
+	}
+	_, reply, err := ws.ReadMessage()
+	if err != nil {
+		ws.Close()
+		return nil, err
+	}
+	ws.SetReadDeadline(time.Time{})
+	fields := strings.Fields(string(reply))
+	if len(fields) == 0 || fields[0] != "good" {
+		ws.Close()
+		return nil, ErrIncompatibleVersion
+	}
+	codecName := CodecJSON
+	if len(fields) > 1 {
+		codecName = fields[1]
+	}
+	c, ok := codecs[codecName]
+	if !ok {
+		ws.Close()
+		return nil, ErrUnknownCodec
+	}
+	var available *angoAvailability
+	if len(fields) > 2 {
+		available = negotiateProcedures(fields[2])
+		if available == nil {
+			ws.Close()
+			return nil, ErrIncompatibleVersion
+		}
+	}
+
+	logger := d.Logger
+	if logger == nil {
+		logger = nopLogger{}
+	}
+	if available != nil {
+		logger.Infof("protocol version differs, %d procedures unavailable", available.unavailable)
+	}
+	logger.Debugf("connected to %s, using codec %s", url, codecName)
+
+	conn := &Conn{
+		ws:          ws,
+		codec:       c,
+		handler:     handler,
+		logger:      logger,
+		available:   available,
+		callbackInc: &incremental.Uint64{},
+		callbacks:   make(map[uint64]chan *angoInMsg),
+		receivers:   make(map[uint64]*connReceiver),
+		streams:     make(map[uint64]*connStream),
+		goingAway:   make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	conn.ctx, conn.cancel = context.WithCancel(context.Background())
+	go conn.run()
+	return conn, nil
+}
+
+// Conn is a connection to the service made with Dial. It provides methods to call the server procedures.
+type Conn struct {
+	ws          *websocket.Conn
+	codec       codec
+	handler     ClientHandler
+	logger      Logger
+	available   *angoAvailability // nil when the protocol versions match, all procedures are available
+	callbackInc *incremental.Uint64
+	writeLock   sync.Mutex
+
+	// ctx is cancelled when the connection closes
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// lock guards callbacks, receivers, streams and err
+	lock      sync.Mutex
+	callbacks map[uint64]chan *angoInMsg // returning server procedure calls, by callback ID
+	receivers map[uint64]*connReceiver   // server stream and subscribe procedure calls, by callback ID
+	streams   map[uint64]*connStream     // client stream procedure calls, by callback ID
+	err       error
+
+	goingAwayOnce sync.Once
+	goingAway     chan struct{}
+	done          chan struct{}
+}
+
+// Close closes the connection. Calls waiting for a response return ErrConnectionClosed.
+func (conn *Conn) Close() error {
+	conn.lock.Lock()
+	if conn.err == nil {
+		conn.err = ErrConnectionClosed
+	}
+	conn.lock.Unlock()
+	conn.writeLock.Lock()
+	_ = conn.ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	conn.writeLock.Unlock()
+	err := conn.ws.Close()
+	<-conn.done
+	return err
+}
+
+// Done returns a channel that is closed when the connection has closed.
+func (conn *Conn) Done() <-chan struct{} {
+	return conn.done
+}
+
+// Err returns the reason the connection closed, or nil while the connection is open.
+func (conn *Conn) Err() error {
+	conn.lock.Lock()
+	defer conn.lock.Unlock()
+	return conn.err
+}
+
+// GoingAway returns a channel that is closed when the server is shutting down.
+// Calls that were already sent are still answered, new calls are rejected with ErrServerShutdown.
+func (conn *Conn) GoingAway() <-chan struct{} {
+	return conn.goingAway
+}
+
+// writeMsg encodes a message with the connection's codec and writes it to the websocket, it is safe for concurrent use.
+func (conn *Conn) writeMsg(v interface{}) error {
+	data, err := conn.codec.marshal(v)
+	if err != nil {
+		return err
+	}
+	conn.writeLock.Lock()
+	defer conn.writeLock.Unlock()
+	return conn.ws.WriteMessage(conn.codec.messageType(), data)
+}
+
+// run reads and handles messages from the server until the connection fails or is closed.
+func (conn *Conn) run() {
+	var err error
+	for {
+		var data []byte
+		_, data, err = conn.ws.ReadMessage()
+		if err != nil {
+			break
+		}
+		inMsg := &angoInMsg{}
+		err = conn.codec.unmarshal(data, inMsg)
+		if err != nil {
+			break
+		}
+		if inMsg.Type == msgTypeBatch {
+			err = conn.handleBatch(inMsg)
+		} else {
+			err = conn.handleMsg(inMsg)
+		}
+		if err != nil {
+			break
+		}
+	}
+	conn.close(err)
+}
+
+// close ends all calls and streams, err is kept as the reason the connection closed.
+func (conn *Conn) close(err error) {
+	conn.lock.Lock()
+	if conn.err == nil {
+		conn.err = err
+		conn.logger.Errorf("connection closed: %v", err)
+	}
+	for id, ch := range conn.callbacks {
+		close(ch)
+		delete(conn.callbacks, id)
+	}
+	for id, r := range conn.receivers {
+		close(r.items)
+		delete(conn.receivers, id)
+	}
+	conn.lock.Unlock()
+	conn.cancel()
+	conn.ws.Close()
+	close(conn.done)
+}
+
+// handleBatch handles all messages in a batch message, in order.
+func (conn *Conn) handleBatch(inMsg *angoInMsg) error {
+	batch := []*angoInMsg{}
+	err := conn.codec.unmarshal(inMsg.Data, &batch)
+	if err != nil {
+		return err
+	}
+	for _, msg := range batch {
+		// batches can't be nested
+		if msg == nil || msg.Type == msgTypeBatch {
+			return ErrInvalidMessageType
+		}
+		err = conn.handleMsg(msg)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleMsg handles a single message from the server.
+func (conn *Conn) handleMsg(inMsg *angoInMsg) error {
+	switch inMsg.Type {
+	case msgTypeRequest:
+		go conn.handleRequest(inMsg)
+	case msgTypeResponse:
+		conn.lock.Lock()
+		callbackCh := conn.callbacks[inMsg.CallbackID]
+		delete(conn.callbacks, inMsg.CallbackID)
+		conn.lock.Unlock()
+		if callbackCh == nil {
+			// the call was abandoned, its context was done
+			return nil
+		}
+		callbackCh <- inMsg
+	case msgTypeGoingAway:
+		conn.logger.Infof("server is going away")
+		conn.goingAwayOnce.Do(func() {
+			close(conn.goingAway)
+		})
+	case msgTypeStreamItem, msgTypeStreamEnd:
+		conn.lock.Lock()
+		receiver := conn.receivers[inMsg.CallbackID]
+		if receiver != nil && inMsg.Type == msgTypeStreamEnd {
+			delete(conn.receivers, inMsg.CallbackID)
+		}
+		conn.lock.Unlock()
+		if receiver == nil {
+			// stream was cancelled, ignore items that were already underway
+			return nil
+		}
+		if !receiver.push(inMsg) {
+			return ErrStreamOverflow
+		}
+	case msgTypeStreamCancel:
+		stream := conn.stream(inMsg.CallbackID)
+		if stream != nil {
+			stream.cancel()
+		}
+	case msgTypeStreamCredit:
+		creditData := &angoCreditData{}
+		err := conn.codec.unmarshal(inMsg.Data, creditData)
+		if err != nil {
+			return err
+		}
+		stream := conn.stream(inMsg.CallbackID)
+		if stream != nil {
+			stream.addCredit(creditData.Credit)
+		}
+	default:
+		return ErrInvalidMessageType
+	}
+	return nil
+}
+
+// handleRequest calls the client procedure requested by inMsg on the handler and writes the response.
+func (conn *Conn) handleRequest(inMsg *angoInMsg) {
+	conn.logger.Debugf("have request: %s", inMsg.Procedure)
+	if conn.handler == nil {
+		conn.reply(inMsg.CallbackID, nil, ErrProcedureUnavailable)
+		return
+	}
+	switch inMsg.Procedure {
+	{{range .Service.ClientProcedures}}
+		case "{{.Name}}":
+			procArgs := &angoClientArgsData{{.CapitalizedName}}{} {{/* var procArgs is referenced by .GoCallArgs */}}
+			err := conn.codec.unmarshal(inMsg.Data, procArgs)
+			if err != nil {
+				conn.logger.Errorf("invalid arguments for {{.Name}}: %v", err)
+				conn.reply(inMsg.CallbackID, nil, err)
+				return
+			}
+			{{if .Stream}}
+				stream := conn.newStream(inMsg.CallbackID)
+				if stream == nil {
+					return
+				}
+				stream.end(conn.handler.{{.CapitalizedName}}( {{.GoCallArgs}}{{if .Args}}, {{end}}&{{.CapitalizedName}}Stream{ctx: stream.ctx, stream: stream} ))
+			{{else if .Oneway}}
+				conn.handler.{{.CapitalizedName}}( {{.GoCallArgs}} )
+			{{else}}
+				procRets := &angoClientRetsData{{.CapitalizedName}}{} {{/* var procRets is referenced by .GoCallRets */}}
+				var procErr error {{/* var procErr is referenced by .GoCallRets */}}
+				{{.GoCallRets}} = conn.handler.{{.CapitalizedName}}( {{.GoCallArgs}} )
+				conn.reply(inMsg.CallbackID, procRets, procErr)
+			{{end}}
+			return
+	{{end}}
+	}
+	conn.reply(inMsg.CallbackID, nil, ErrProcedureUnavailable)
+}
+
+// reply writes the response for a call from the server, nothing is written for oneway calls.
+func (conn *Conn) reply(callbackID uint64, rets interface{}, err error) {
+	if callbackID == 0 {
+		return
+	}
+	outMsg := &angoOutMsg{
+		Type:       msgTypeResponse,
+		CallbackID: callbackID,
+		Data:       rets,
+	}
+	if err != nil {
+		outMsg.Data = nil
+		outMsg.Error = &angoOutError{
+			Type:    "errorReturned",
+			Message: err.Error(),
+		}
+		if err == ErrProcedureUnavailable {
+			outMsg.Error.Type = "procedureUnavailable"
+		}
+	}
+	err = conn.writeMsg(outMsg)
+	if err != nil {
+		conn.logger.Errorf("error writing response: %v", err)
+	}
+}
+
+// decodeError decodes the error object from a message, the error types sent by the server for rejected calls
+// are returned as ErrProcedureUnavailable, ErrServerShutdown and ErrRateLimited.
+func (conn *Conn) decodeError(raw json.RawMessage) error {
+	errObj := &angoOutError{}
+	err := conn.codec.unmarshal(raw, errObj)
+	if err != nil {
+		return err
+	}
+	switch errObj.Type {
+	case "procedureUnavailable":
+		return ErrProcedureUnavailable
+	case "goingAway":
+		return ErrServerShutdown
+	case "rateLimited":
+		return ErrRateLimited
+	}
+	return errors.New(errObj.Message)
+}
+
+// call calls a server procedure. When rets is nil the call is oneway,
+// otherwise call waits for the response and decodes the return values into rets.
+func (conn *Conn) call(ctx context.Context, procedure string, args interface{}, rets interface{}) error {
+	if !conn.available.server(procedure) {
+		return ErrProcedureUnavailable
+	}
+	outMsg := &angoOutMsg{
+		Type:      msgTypeRequest,
+		Procedure: procedure,
+		Data:      args,
+	}
+	if rets == nil {
+		// oneway, only write message
+		return conn.writeMsg(outMsg)
+	}
+
+	callbackCh := make(chan *angoInMsg, 1)
+	outMsg.CallbackID = conn.callbackInc.Next()
+	conn.lock.Lock()
+	if conn.err != nil {
+		conn.lock.Unlock()
+		return conn.err
+	}
+	conn.callbacks[outMsg.CallbackID] = callbackCh
+	conn.lock.Unlock()
+
+	err := conn.writeMsg(outMsg)
+	if err != nil {
+		conn.removeCallback(outMsg.CallbackID)
+		return err
+	}
+
+	select {
+	case respMsg, ok := <-callbackCh:
+		if !ok {
+			return ErrConnectionClosed
+		}
+		if respMsg.Error != nil {
+			return conn.decodeError(respMsg.Error)
+		}
+		return conn.codec.unmarshal(respMsg.Data, rets)
+	case <-ctx.Done():
+		conn.removeCallback(outMsg.CallbackID)
+		return ctx.Err()
+	}
+}
+
+// removeCallback unregisters the callback for a call that won't wait for its response.
+func (conn *Conn) removeCallback(id uint64) {
+	conn.lock.Lock()
+	delete(conn.callbacks, id)
+	conn.lock.Unlock()
+}
+
+// callStream starts a server stream or subscribe procedure, items are received by the returned receiver.
+func (conn *Conn) callStream(procedure string, args interface{}) (*connReceiver, error) {
+	if !conn.available.server(procedure) {
+		return nil, ErrProcedureUnavailable
+	}
+	r := &connReceiver{
+		conn:  conn,
+		id:    conn.callbackInc.Next(),
+		items: make(chan *angoInMsg, streamWindow+1),
+	}
+	conn.lock.Lock()
+	if conn.err != nil {
+		conn.lock.Unlock()
+		return nil, conn.err
+	}
+	conn.receivers[r.id] = r
+	conn.lock.Unlock()
+	err := conn.writeMsg(&angoOutMsg{
+		Type:       msgTypeRequest,
+		Procedure:  procedure,
+		CallbackID: r.id,
+		Data:       args,
+	})
+	if err != nil {
+		r.remove()
+		return nil, err
+	}
+	return r, nil
+}
+
+// connReceiver is the consuming side of a server stream or subscribe procedure call.
+type connReceiver struct {
+	conn     *Conn
+	id       uint64
+	items    chan *angoInMsg // item and end messages, buffered for a full window
+	consumed int             // items consumed since credit was last granted
+	err      error           // set when the stream has ended
+}
+
+// remove unregisters the receiver.
+func (r *connReceiver) remove() {
+	r.conn.lock.Lock()
+	delete(r.conn.receivers, r.id)
+	r.conn.lock.Unlock()
+}
+
+// push adds an item or end message for the receiver. It returns false when the server didn't respect the stream window.
+func (r *connReceiver) push(inMsg *angoInMsg) bool {
+	select {
+	case r.items <- inMsg:
+		return true
+	default:
+		return false
+	}
+}
+
+// recv decodes the next item into v. It returns io.EOF when the stream has ended normally.
+// When ctx is done before an item arrives, the stream is cancelled.
+func (r *connReceiver) recv(ctx context.Context, v interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	if err := ctx.Err(); err != nil {
+		r.cancel()
+		r.err = err
+		return err
+	}
+	var inMsg *angoInMsg
+	var ok bool
+	select {
+	case inMsg, ok = <-r.items:
+	case <-ctx.Done():
+		r.cancel()
+		r.err = ctx.Err()
+		return r.err
+	}
+	if !ok {
+		r.err = ErrConnectionClosed
+		return r.err
+	}
+	if inMsg.Type == msgTypeStreamEnd {
+		r.err = io.EOF
+		if inMsg.Error != nil {
+			r.err = r.conn.decodeError(inMsg.Error)
+		}
+		return r.err
+	}
+	r.consumed++
+	if r.consumed >= streamWindow/2 {
+		err := r.conn.writeMsg(&angoOutMsg{
+			Type:       msgTypeStreamCredit,
+			CallbackID: r.id,
+			Data:       &angoCreditData{Credit: r.consumed},
+		})
+		if err != nil {
+			return err
+		}
+		r.consumed = 0
+	}
+	return r.conn.codec.unmarshal(inMsg.Data, v)
+}
+
+// cancel cancels the stream, the server is asked to stop producing items.
+func (r *connReceiver) cancel() {
+	if r.err != nil {
+		return
+	}
+	r.err = ErrStreamCancelled
+	r.remove()
+	_ = r.conn.writeMsg(&angoOutMsg{
+		Type:       msgTypeStreamCancel,
+		CallbackID: r.id,
+	})
+}
+
+// connStream is the producing side of a client stream procedure call made by the server.
+type connStream struct {
+	conn   *Conn
+	id     uint64
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	lock   sync.Mutex
+	credit int
+	notify chan struct{} // receives when credit is added
+}
+
+// newStream registers a new stream for the client stream procedure call with given id.
+// It returns nil when a stream with the id already exists.
+func (conn *Conn) newStream(id uint64) *connStream {
+	s := &connStream{
+		conn:   conn,
+		id:     id,
+		credit: streamWindow,
+		notify: make(chan struct{}, 1),
+	}
+	s.ctx, s.cancel = context.WithCancel(conn.ctx)
+	conn.lock.Lock()
+	defer conn.lock.Unlock()
+	if conn.streams[id] != nil {
+		s.cancel()
+		return nil
+	}
+	conn.streams[id] = s
+	return s
+}
+
+// stream returns the stream with given id, or nil when the stream doesn't exist (anymore).
+func (conn *Conn) stream(id uint64) *connStream {
+	conn.lock.Lock()
+	defer conn.lock.Unlock()
+	return conn.streams[id]
+}
+
+// addCredit allows the stream to send n more items.
+func (s *connStream) addCredit(n int) {
+	s.lock.Lock()
+	s.credit += n
+	s.lock.Unlock()
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// takeCredit uses one credit, it returns false when the stream has no credit left.
+func (s *connStream) takeCredit() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.credit == 0 {
+		return false
+	}
+	s.credit--
+	return true
+}
+
+// send sends an item, it waits for credit when the stream has none left.
+func (s *connStream) send(item interface{}) error {
+	for !s.takeCredit() {
+		select {
+		case <-s.notify:
+		case <-s.ctx.Done():
+			return ErrStreamCancelled
+		}
+	}
+	if s.ctx.Err() != nil {
+		return ErrStreamCancelled
+	}
+	return s.conn.writeMsg(&angoOutMsg{
+		Type:       msgTypeStreamItem,
+		CallbackID: s.id,
+		Data:       item,
+	})
+}
+
+// end unregisters the stream and sends the end of the stream to the server.
+func (s *connStream) end(err error) {
+	s.cancel()
+	s.conn.lock.Lock()
+	delete(s.conn.streams, s.id)
+	s.conn.lock.Unlock()
+	outMsg := &angoOutMsg{
+		Type:       msgTypeStreamEnd,
+		CallbackID: s.id,
+	}
+	if err != nil {
+		outMsg.Error = &angoOutError{
+			Type:    "errorReturned",
+			Message: err.Error(),
+		}
+	}
+	_ = s.conn.writeMsg(outMsg)
+}
+
+{{range .Service.ServerProcedures}}
+	{{if .Oneway}}
+		// {{.CapitalizedName}} is a ango procedure defined in the .ango file.
+		// This is a oneway procedure, it returns as soon as the call has been sent to the server.
+		func (conn *Conn) {{.CapitalizedName}}( ctx context.Context{{if .Args}}, {{.GoArgs}}{{end}} ) error {
+			conn.logger.Debugf("calling oneway server procedure {{.Name}}")
+			return conn.call(ctx, "{{.Name}}", &angoServerArgsData{{.CapitalizedName}}{
+				{{range .Args}}
+					{{.CapitalizedName}}: {{.Name}},{{end}}
+			}, nil)
+		}
+	{{else if or .Stream .Subscribe}}
+		// {{.CapitalizedName}}Item contains the values for a single {{if .Stream}}item of the stream{{else}}value of the subscription{{end}} Conn.{{.CapitalizedName}}.
+		type {{.CapitalizedName}}Item struct {
+			{{range .Rets}}
+				{{.CapitalizedName}} {{.GoTypeName}}{{end}}
+		}
+
+		// {{.CapitalizedName}}Receiver receives the {{if .Stream}}items for a call to the stream{{else}}values for the subscription{{end}} Conn.{{.CapitalizedName}}.
+		type {{.CapitalizedName}}Receiver struct {
+			receiver *connReceiver
+		}
+
+		// Recv waits for and returns the next {{if .Stream}}item{{else}}value{{end}}. Recv returns io.EOF when the {{if .Stream}}stream{{else}}subscription{{end}} has ended normally.
+		// Any other error indicates the {{if .Stream}}stream{{else}}subscription{{end}} failed, or the procedure returned with an error.
+		// When ctx is done before the next {{if .Stream}}item{{else}}value{{end}} arrives, the {{if .Stream}}stream{{else}}subscription{{end}} is cancelled.
+		// Recv is not safe for concurrent use.
+		func (r *{{.CapitalizedName}}Receiver) Recv(ctx context.Context) (*{{.CapitalizedName}}Item, error) {
+			retsData := &angoServerRetsData{{.CapitalizedName}}{}
+			err := r.receiver.recv(ctx, retsData)
+			if err != nil {
+				return nil, err
+			}
+			return &{{.CapitalizedName}}Item{
+				{{range .Rets}}
+					{{.CapitalizedName}}: retsData.{{.CapitalizedName}},{{end}}
+			}, nil
+		}
+
+		// Cancel {{if .Stream}}cancels the stream{{else}}unsubscribes{{end}}. Recv returns ErrStreamCancelled after Cancel was called.
+		// Cancel must not be called concurrently with Recv.
+		func (r *{{.CapitalizedName}}Receiver) Cancel() {
+			r.receiver.cancel()
+		}
+
+		// {{.CapitalizedName}} is a ango {{if .Stream}}stream{{else}}subscribe{{end}} procedure defined in the .ango file.
+		// {{if .Stream}}Items produced by the server{{else}}Values published by the server{{end}} are received with the returned {{.CapitalizedName}}Receiver.
+		func (conn *Conn) {{.CapitalizedName}}( {{.GoArgs}} ) (*{{.CapitalizedName}}Receiver, error) {
+			conn.logger.Debugf("calling {{if .Stream}}stream{{else}}subscribe{{end}} server procedure {{.Name}}")
+			receiver, err := conn.callStream("{{.Name}}", &angoServerArgsData{{.CapitalizedName}}{
+				{{range .Args}}
+					{{.CapitalizedName}}: {{.Name}},{{end}}
+			})
+			if err != nil {
+				return nil, err
+			}
+			return &{{.CapitalizedName}}Receiver{receiver: receiver}, nil
+		}
+	{{else}}
+		// {{.CapitalizedName}} is a ango procedure defined in the .ango file.
+		// It waits for the server to respond, or until ctx is done.
+		func (conn *Conn) {{.CapitalizedName}}( ctx context.Context{{if .Args}}, {{.GoArgs}}{{end}} )( {{.GoRets}} ) {
+			conn.logger.Debugf("calling server procedure {{.Name}}")
+			retsData := &angoServerRetsData{{.CapitalizedName}}{}
+			err = conn.call(ctx, "{{.Name}}", &angoServerArgsData{{.CapitalizedName}}{
+				{{range .Args}}
+					{{.CapitalizedName}}: {{.Name}},{{end}}
+			}, retsData)
+			if err != nil {
+				return
+			}
+			{{range .Rets}}
+				{{.Name}} = retsData.{{.CapitalizedName}}{{end}}
+			return
+		}
+	{{end}}
+{{end}}
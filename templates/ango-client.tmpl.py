@@ -0,0 +1,931 @@
+# WARNING This is generated code by the ango tool (github.com/GeertJohan/ango)
+# DO NOT EDIT unless you know what you're doing!
+
+"""Client for the {{.Service.Name}} ango service.
+
+The module only uses the Python 3 standard library, it includes a small websocket client.
+
+    client = {{.Service.CapitalizedName}}Client("ws://localhost:8080/websocket-ango-{{.Service.Name}}", handler)
+    ...
+    client.close()
+
+Calls to server procedures block until the response arrives, a failed call raises AngoError.
+Requests from the server are handled by the methods of a {{.Service.CapitalizedName}}Handler subclass, each in its own thread.
+Messages are encoded as JSON, or as MessagePack with codec="msgpack".
+"""
+
+import base64
+import dataclasses
+import functools
+import hashlib
+import json
+import os
+import queue
+import socket
+import ssl
+import struct
+import threading
+import typing
+import urllib.parse
+from concurrent.futures import Future
+from dataclasses import dataclass, field
+from typing import Any, Callable, Dict, Iterator, List, Optional
+
+SERVICE_NAME = "{{.Service.Name}}"
+PROTOCOL_VERSION = "{{.ProtocolVersion}}"
+# signature of every procedure, procedures with the same signature on both sides can be called when the versions differ
+SIGNATURES = "{{.SignatureSet}}"
+
+# number of stream items that may be sent before the receiving side must grant credit
+STREAM_WINDOW = 16
+
+
+class AngoError(Exception):
+    """An error from the ango protocol, type is one of unknown, errorReturned, goingAway, rateLimited,
+    procedureUnavailable, or the errors created by the client: versionMismatch and connectionClosed."""
+
+    def __init__(self, type: str, message: str):
+        super().__init__(message)
+        self.type = type
+        self.message = message
+
+    def __repr__(self):
+        return "AngoError(%r, %r)" % (self.type, self.message)
+
+
+def _error_from_json(v: Any) -> AngoError:
+    # older peers send the error as string
+    if isinstance(v, str):
+        return AngoError("errorReturned", v)
+    if isinstance(v, dict):
+        return AngoError(str(v.get("type") or "unknown"), str(v.get("message") or ""))
+    return AngoError("unknown", str(v))
+
+
+@dataclass
+class HandlerOptions:
+    """Given as last argument to a handler method, meta holds the meta from the request."""
+    meta: Dict[str, str] = field(default_factory=dict)
+
+
+# TYPES, as defined in .ango file
+{{range .Service.PyDeclarations}}
+
+{{.}}{{end}}
+
+# RESULTS, the return values of the procedures
+{{range .Service.PyProcedures "server"}}{{if and .Rets (not .Oneway)}}
+
+@dataclass
+class {{.PyRetsName}}:
+{{range .Rets}}    {{.PyName}}: {{.PyTypeName}} = field({{.PyDefault}}, metadata={"json": "{{.Name}}"})
+{{end}}{{end}}{{end}}{{range .Service.PyProcedures "client"}}{{if and .Rets (not .Oneway)}}
+
+@dataclass
+class {{.PyRetsName}}:
+{{range .Rets}}    {{.PyName}}: {{.PyTypeName}} = field({{.PyDefault}}, metadata={"json": "{{.Name}}"})
+{{end}}{{end}}{{end}}
+
+def _resolve(tp: Any) -> Any:
+    # named types are referenced by name, they can be declared after the type that uses them
+    if isinstance(tp, str):
+        return globals()[tp]
+    if isinstance(tp, typing.ForwardRef):
+        return globals()[tp.__forward_arg__]
+    return tp
+
+
+@functools.lru_cache(maxsize=None)
+def _fields(cls: Any) -> List[Any]:
+    hints = typing.get_type_hints(cls, globals())
+    return [(f.name, f.metadata.get("json", f.name), hints[f.name]) for f in dataclasses.fields(cls)]
+
+
+def _to_json(v: Any) -> Any:
+    """Returns the JSON value for v, like encoding/json encodes the Go value."""
+    if dataclasses.is_dataclass(v) and not isinstance(v, type):
+        return {json_name: _to_json(getattr(v, name)) for name, json_name, _ in _fields(type(v))}
+    if isinstance(v, (bytes, bytearray)):
+        return base64.b64encode(v).decode("ascii")
+    if isinstance(v, (list, tuple)):
+        return [_to_json(x) for x in v]
+    if isinstance(v, dict):
+        return {_key_to_json(k): _to_json(x) for k, x in v.items()}
+    return v
+
+
+def _to_msgpack(v: Any) -> Any:
+    """Returns the MessagePack value for v, like _to_json but bytes stay binary and map keys keep their type."""
+    if dataclasses.is_dataclass(v) and not isinstance(v, type):
+        return {json_name: _to_msgpack(getattr(v, name)) for name, json_name, _ in _fields(type(v))}
+    if isinstance(v, (list, tuple)):
+        return [_to_msgpack(x) for x in v]
+    if isinstance(v, dict):
+        return {k: _to_msgpack(x) for k, x in v.items()}
+    return v
+
+
+def _key_to_json(k: Any) -> str:
+    if isinstance(k, bool):
+        return "true" if k else "false"
+    return str(k)
+
+
+def _from_json(tp: Any, v: Any) -> Any:
+    """Returns the value of type tp for the decoded JSON or MessagePack value v."""
+    tp = _resolve(tp)
+    origin = typing.get_origin(tp)
+    if v is None:
+        # encoding/json encodes nil slices and maps as null
+        if origin is list:
+            return []
+        if origin is dict:
+            return {}
+        if tp is bytes:
+            return b""
+        return None
+    if origin is list:
+        (elem,) = typing.get_args(tp)
+        return [_from_json(elem, x) for x in v]
+    if origin is dict:
+        key, elem = typing.get_args(tp)
+        return {_key_from_json(key, k): _from_json(elem, x) for k, x in v.items()}
+    if dataclasses.is_dataclass(tp):
+        # like encoding/json, field names are matched case-insensitive
+        values = {k.lower(): x for k, x in v.items()}
+        kwargs = {}
+        for name, json_name, hint in _fields(tp):
+            if json_name.lower() in values:
+                kwargs[name] = _from_json(hint, values[json_name.lower()])
+        return tp(**kwargs)
+    if tp is bytes:
+        # MessagePack sends binary values, JSON base64 strings
+        return bytes(v) if isinstance(v, (bytes, bytearray)) else base64.b64decode(v)
+    if tp is float:
+        return float(v)
+    if tp is int:
+        return int(v)
+    return v
+
+
+def _key_from_json(tp: Any, k: Any) -> Any:
+    # JSON keys are always strings, MessagePack keys keep their type
+    tp = _resolve(tp)
+    if tp is bool:
+        return k if isinstance(k, bool) else k == "true"
+    if tp is int:
+        return int(k)
+    if tp is float:
+        return float(k)
+    return k
+
+
+def _check_int(name: str, v: Any, minimum: int, maximum: int):
+    if not isinstance(v, int) or isinstance(v, bool):
+        raise TypeError("argument %s must be int" % name)
+    if v < minimum or v > maximum:
+        raise ValueError("argument %s is out of range [%d, %d]" % (name, minimum, maximum))
+
+
+def _msgpack_encode(v: Any) -> bytes:
+    """Encodes v, built from None, bool, int, float, str, bytes, list and dict, as MessagePack."""
+    out = bytearray()
+    _msgpack_pack(v, out)
+    return bytes(out)
+
+
+def _msgpack_pack(v: Any, out: bytearray):
+    if v is None:
+        out.append(0xC0)
+    elif v is True:
+        out.append(0xC3)
+    elif v is False:
+        out.append(0xC2)
+    elif isinstance(v, int):
+        # the smallest encoding that holds v
+        if -32 <= v <= 0x7F:
+            out += struct.pack("!b", v)
+        elif 0 <= v <= 0xFF:
+            out += struct.pack("!BB", 0xCC, v)
+        elif 0 <= v <= 0xFFFF:
+            out += struct.pack("!BH", 0xCD, v)
+        elif 0 <= v <= 0xFFFFFFFF:
+            out += struct.pack("!BI", 0xCE, v)
+        elif v >= 0:
+            out += struct.pack("!BQ", 0xCF, v)
+        elif v >= -0x80:
+            out += struct.pack("!Bb", 0xD0, v)
+        elif v >= -0x8000:
+            out += struct.pack("!Bh", 0xD1, v)
+        elif v >= -0x80000000:
+            out += struct.pack("!Bi", 0xD2, v)
+        else:
+            out += struct.pack("!Bq", 0xD3, v)
+    elif isinstance(v, float):
+        out += struct.pack("!Bd", 0xCB, v)
+    elif isinstance(v, str):
+        b = v.encode("utf-8")
+        _msgpack_header(out, len(b), 0xA0, 32, (0xD9, 0xDA, 0xDB))
+        out += b
+    elif isinstance(v, (bytes, bytearray)):
+        _msgpack_header(out, len(v), None, 0, (0xC4, 0xC5, 0xC6))
+        out += v
+    elif isinstance(v, (list, tuple)):
+        _msgpack_header(out, len(v), 0x90, 16, (None, 0xDC, 0xDD))
+        for x in v:
+            _msgpack_pack(x, out)
+    elif isinstance(v, dict):
+        _msgpack_header(out, len(v), 0x80, 16, (None, 0xDE, 0xDF))
+        for k, x in v.items():
+            _msgpack_pack(k, out)
+            _msgpack_pack(x, out)
+    else:
+        raise TypeError("msgpack: unsupported type %s" % type(v).__name__)
+
+
+def _msgpack_header(out: bytearray, n: int, fix: Optional[int], fix_limit: int, codes: tuple):
+    """Writes the header of a str, bin, array or map with n elements. codes are the type bytes for an 8, 16 and 32 bit
+    length, arrays and maps don't have an 8 bit length."""
+    code8, code16, code32 = codes
+    if fix is not None and n < fix_limit:
+        out.append(fix | n)
+    elif code8 is not None and n <= 0xFF:
+        out += struct.pack("!BB", code8, n)
+    elif n <= 0xFFFF:
+        out += struct.pack("!BH", code16, n)
+    else:
+        out += struct.pack("!BI", code32, n)
+
+
+class _MsgpackDecoder:
+    """Decodes a MessagePack message. Maps become dicts, binary values bytes. Invalid data raises ValueError."""
+
+    def __init__(self, data: bytes):
+        self._data = data
+        self._pos = 0
+
+    def decode(self) -> Any:
+        v = self._value()
+        if self._pos != len(self._data):
+            raise ValueError("msgpack: trailing data after value")
+        return v
+
+    def _read(self, n: int) -> bytes:
+        if self._pos + n > len(self._data):
+            raise ValueError("msgpack: unexpected end of data")
+        b = self._data[self._pos:self._pos + n]
+        self._pos += n
+        return b
+
+    def _unpack(self, fmt: str) -> Any:
+        return struct.unpack(fmt, self._read(struct.calcsize(fmt)))[0]
+
+    def _value(self) -> Any:
+        c = self._unpack("!B")
+        if c <= 0x7F:
+            return c
+        if c >= 0xE0:
+            return c - 0x100
+        if c & 0xF0 == 0x80:
+            return self._map(c & 0x0F)
+        if c & 0xF0 == 0x90:
+            return [self._value() for _ in range(c & 0x0F)]
+        if c & 0xE0 == 0xA0:
+            return self._read(c & 0x1F).decode("utf-8")
+        if c == 0xC0:
+            return None
+        if c in (0xC2, 0xC3):
+            return c == 0xC3
+        if c in (0xC4, 0xC5, 0xC6):
+            return self._read(self._unpack(("!B", "!H", "!I")[c - 0xC4]))
+        if c == 0xCA:
+            return self._unpack("!f")
+        if c == 0xCB:
+            return self._unpack("!d")
+        if 0xCC <= c <= 0xD3:
+            return self._unpack(("!B", "!H", "!I", "!Q", "!b", "!h", "!i", "!q")[c - 0xCC])
+        if c in (0xD9, 0xDA, 0xDB):
+            return self._read(self._unpack(("!B", "!H", "!I")[c - 0xD9])).decode("utf-8")
+        if c in (0xDC, 0xDD):
+            return [self._value() for _ in range(self._unpack(("!H", "!I")[c - 0xDC]))]
+        if c in (0xDE, 0xDF):
+            return self._map(self._unpack(("!H", "!I")[c - 0xDE]))
+        raise ValueError("msgpack: unsupported type byte 0x%02x" % c)
+
+    def _map(self, n: int) -> Dict[Any, Any]:
+        m = {}
+        for _ in range(n):
+            k = self._value()
+            m[k] = self._value()
+        return m
+
+
+class WebSocket:
+    """Minimal websocket client (RFC 6455) on top of the socket module, used when no transport is given.
+
+    A transport is any object with the methods send(message), recv() returning the next message or None when the
+    connection closed, and close(). Messages are str for text messages and bytes for binary messages.
+    """
+
+    _GUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+    def __init__(self, url: str, timeout: Optional[float] = 10.0, headers: Optional[Dict[str, str]] = None):
+        u = urllib.parse.urlsplit(url)
+        secure = u.scheme == "wss"
+        port = u.port or (443 if secure else 80)
+        sock = socket.create_connection((u.hostname, port), timeout)
+        if secure:
+            sock = ssl.create_default_context().wrap_socket(sock, server_hostname=u.hostname)
+        self._sock = sock
+        self._buf = b""
+        self._lock = threading.Lock()
+        self._closed = False
+
+        key = base64.b64encode(os.urandom(16)).decode("ascii")
+        path = u.path or "/"
+        if u.query:
+            path += "?" + u.query
+        lines = [
+            "GET %s HTTP/1.1" % path,
+            "Host: %s" % u.netloc,
+            "Upgrade: websocket",
+            "Connection: Upgrade",
+            "Sec-WebSocket-Key: %s" % key,
+            "Sec-WebSocket-Version: 13",
+        ]
+        for name, value in (headers or {}).items():
+            lines.append("%s: %s" % (name, value))
+        sock.sendall(("\r\n".join(lines) + "\r\n\r\n").encode("latin-1"))
+
+        while b"\r\n\r\n" not in self._buf:
+            self._fill()
+        head, self._buf = self._buf.split(b"\r\n\r\n", 1)
+        status, *header_lines = head.decode("latin-1").split("\r\n")
+        if len(status.split(" ")) < 2 or status.split(" ")[1] != "101":
+            sock.close()
+            raise ConnectionError("websocket handshake failed: " + status)
+        response = {}
+        for line in header_lines:
+            name, _, value = line.partition(":")
+            response[name.strip().lower()] = value.strip()
+        accept = base64.b64encode(hashlib.sha1((key + self._GUID).encode("ascii")).digest()).decode("ascii")
+        if response.get("sec-websocket-accept") != accept:
+            sock.close()
+            raise ConnectionError("websocket handshake failed: invalid Sec-WebSocket-Accept")
+        sock.settimeout(None)
+
+    def _fill(self):
+        data = self._sock.recv(65536)
+        if not data:
+            raise ConnectionError("connection closed")
+        self._buf += data
+
+    def _read(self, n: int) -> bytes:
+        while len(self._buf) < n:
+            self._fill()
+        data, self._buf = self._buf[:n], self._buf[n:]
+        return data
+
+    def _send_frame(self, opcode: int, payload: bytes):
+        # frames from the client are masked
+        header = bytes([0x80 | opcode])
+        n = len(payload)
+        if n < 126:
+            header += bytes([0x80 | n])
+        elif n < 65536:
+            header += bytes([0x80 | 126]) + struct.pack("!H", n)
+        else:
+            header += bytes([0x80 | 127]) + struct.pack("!Q", n)
+        mask = os.urandom(4)
+        masked = bytes(b ^ mask[i % 4] for i, b in enumerate(payload))
+        with self._lock:
+            self._sock.sendall(header + mask + masked)
+
+    def send(self, message: Any):
+        if isinstance(message, str):
+            self._send_frame(0x1, message.encode("utf-8"))
+        else:
+            self._send_frame(0x2, bytes(message))
+
+    def recv(self) -> Any:
+        message = b""
+        # opcode of the first frame, continuation frames have opcode 0
+        text = True
+        while True:
+            try:
+                b0, b1 = self._read(2)
+                n = b1 & 0x7F
+                if n == 126:
+                    (n,) = struct.unpack("!H", self._read(2))
+                elif n == 127:
+                    (n,) = struct.unpack("!Q", self._read(8))
+                mask = self._read(4) if b1 & 0x80 else None
+                payload = self._read(n)
+            except (OSError, ValueError):
+                return None
+            if mask:
+                payload = bytes(b ^ mask[i % 4] for i, b in enumerate(payload))
+            opcode = b0 & 0x0F
+            if opcode == 0x8:
+                # echo the close frame and stop
+                try:
+                    self._send_frame(0x8, payload[:2])
+                except OSError:
+                    pass
+                self.close()
+                return None
+            if opcode == 0x9:
+                self._send_frame(0xA, payload)
+                continue
+            if opcode == 0xA:
+                continue
+            if opcode != 0x0:
+                text = opcode == 0x1
+            message += payload
+            if b0 & 0x80:
+                return message.decode("utf-8") if text else message
+
+    def close(self):
+        if self._closed:
+            return
+        self._closed = True
+        try:
+            self._send_frame(0x8, struct.pack("!H", 1000))
+        except OSError:
+            pass
+        try:
+            self._sock.shutdown(socket.SHUT_RDWR)
+        except OSError:
+            pass
+        self._sock.close()
+
+
+class Stream:
+    """Items of a server stream procedure. Iterate to receive the items, iteration raises AngoError when the stream
+    ends with an error. Stopping early requires cancel(), or use the stream as context manager."""
+
+    _end = object()
+
+    def __init__(self, conn: "_Connection", cb_id: int, item_type: Any):
+        self._conn = conn
+        self._cb_id = cb_id
+        self._item_type = item_type
+        self._items: "queue.Queue[Any]" = queue.Queue()
+        self._consumed = 0
+        self._ended = False
+
+    def __iter__(self) -> Iterator[Any]:
+        return self
+
+    def __next__(self) -> Any:
+        if self._ended and self._items.empty():
+            raise StopIteration
+        item = self._items.get()
+        if item is Stream._end:
+            self._ended = True
+            raise StopIteration
+        if isinstance(item, AngoError):
+            self._ended = True
+            raise item
+        self._consumed += 1
+        if self._consumed >= STREAM_WINDOW // 2:
+            self._conn.send({"type": "credit", "cb_id": self._cb_id, "data": {"credit": self._consumed}})
+            self._consumed = 0
+        return _from_json(self._item_type, item)
+
+    def __enter__(self):
+        return self
+
+    def __exit__(self, *exc):
+        self.cancel()
+
+    def cancel(self):
+        """Stops the stream, items that are underway are dropped."""
+        if self._ended:
+            return
+        self._ended = True
+        self._conn.end_stream(self._cb_id)
+        self._conn.send({"type": "cancel", "cb_id": self._cb_id})
+        self._items.put(Stream._end)
+
+    def _put(self, item: Any):
+        self._items.put(item)
+
+
+class Subscription:
+    """Updates of a server subscribe procedure. Iterating yields every new value, the latest is kept in value.
+    When the procedure has a single return value that value is used, otherwise the item holding all values."""
+
+    def __init__(self, stream: Stream, value_name: Optional[str]):
+        self._stream = stream
+        self._value_name = value_name
+        self.value: Any = None
+
+    def __iter__(self) -> Iterator[Any]:
+        return self
+
+    def __next__(self) -> Any:
+        item = next(self._stream)
+        self.value = item if self._value_name is None else getattr(item, self._value_name)
+        return self.value
+
+    def __enter__(self):
+        return self
+
+    def __exit__(self, *exc):
+        self.unsubscribe()
+
+    def unsubscribe(self):
+        self._stream.cancel()
+
+
+class StreamSender:
+    """Given to the handler of a client stream procedure. send() blocks while the server hasn't granted credit,
+    it returns False when the server cancelled the stream."""
+
+    def __init__(self, conn: "_Connection", cb_id: int):
+        self._conn = conn
+        self._cb_id = cb_id
+        self._credit = STREAM_WINDOW
+        self._cond = threading.Condition()
+        self._cancelled = False
+        self._ended = False
+        self._cancel_fns: List[Callable[[], None]] = []
+
+    def send(self, item: Any) -> bool:
+        with self._cond:
+            while self._credit == 0 and not self._cancelled:
+                self._cond.wait()
+            if self._cancelled or self._ended:
+                return False
+            self._credit -= 1
+        self._conn.send({"type": "item", "cb_id": self._cb_id, "data": item})
+        return True
+
+    def end(self, err: Optional[str] = None):
+        """Ends the stream, with an error message when err is given."""
+        with self._cond:
+            if self._cancelled or self._ended:
+                return
+            self._ended = True
+        self._conn.end_producer(self._cb_id)
+        message: Dict[str, Any] = {"type": "end", "cb_id": self._cb_id}
+        if err is not None:
+            message["error"] = {"type": "errorReturned", "message": err}
+        self._conn.send(message)
+
+    def on_cancel(self, fn: Callable[[], None]):
+        self._cancel_fns.append(fn)
+
+    def is_cancelled(self) -> bool:
+        return self._cancelled
+
+    def _grant(self, credit: int):
+        with self._cond:
+            self._credit += credit
+            self._cond.notify_all()
+
+    def _cancel(self):
+        with self._cond:
+            self._cancelled = True
+            self._cond.notify_all()
+        for fn in self._cancel_fns:
+            fn()
+
+
+class _Connection:
+    """Runs the protocol over a transport: the handshake, requests and responses, and streams."""
+
+    def __init__(self, transport: Any, dispatch: Callable[["_Connection", Dict[str, Any]], None],
+                 trace_context: Optional[Callable[[str], Optional[Dict[str, str]]]],
+                 on_going_away: Optional[Callable[[], None]], timeout: Optional[float], codec: str):
+        self._transport = transport
+        self._dispatch = dispatch
+        self._trace_context = trace_context
+        self._on_going_away = on_going_away
+        self._timeout = timeout
+        self._lock = threading.Lock()
+        self._cb_id = 0
+        self._callbacks: Dict[int, Future] = {}
+        self._streams: Dict[int, Stream] = {}
+        self._producers: Dict[int, StreamSender] = {}
+        self._stop_error: Optional[AngoError] = None
+        self._going_away = False
+        # server procedures that can be called when the protocol versions differ, None when all procedures can be called
+        self._available: Optional[set] = None
+
+        # send version string, followed by the codecs and the signatures
+        transport.send(PROTOCOL_VERSION + (" msgpack,json " if codec == "msgpack" else " json ") + SIGNATURES)
+        reply = transport.recv()
+        handshake = (reply if isinstance(reply, str) else "").split(" ")
+        if handshake[0] != "good":
+            transport.close()
+            if handshake[0] == "invalid":
+                raise AngoError("versionMismatch", "version mismatch")
+            raise AngoError("connectionClosed", "connection closed during handshake")
+        # the negotiated codec, a plain "good" means json
+        self.codec = "msgpack" if len(handshake) > 1 and handshake[1] == "msgpack" else "json"
+        if len(handshake) > 2:
+            self._available = _available_procedures(handshake[2])
+
+        self._reader = threading.Thread(target=self._read, name="ango-" + SERVICE_NAME, daemon=True)
+        self._reader.start()
+
+    def send(self, message: Dict[str, Any]):
+        """Encodes the message with the negotiated codec and sends it, values in the message are converted."""
+        if self._stop_error is not None:
+            return
+        try:
+            if self.codec == "msgpack":
+                self._transport.send(_msgpack_encode(_to_msgpack(message)))
+            else:
+                self._transport.send(json.dumps(_to_json(message)))
+        except OSError:
+            self._stop(AngoError("connectionClosed", "connection closed"))
+
+    def close(self):
+        self._transport.close()
+        self._stop(AngoError("connectionClosed", "connection closed"))
+
+    def _new_call(self, name: str, meta: Optional[Dict[str, str]]) -> Dict[str, Any]:
+        if self._stop_error is not None:
+            raise self._stop_error
+        if self._going_away:
+            raise AngoError("goingAway", "server is going away")
+        if self._available is not None and name not in self._available:
+            raise AngoError("procedureUnavailable", "procedure unavailable")
+        request: Dict[str, Any] = {"type": "req", "procedure": name}
+        call_meta: Dict[str, str] = {}
+        if self._trace_context is not None:
+            tc = self._trace_context(name) or {}
+            for key in ("traceparent", "baggage"):
+                if isinstance(tc.get(key), str):
+                    call_meta[key] = tc[key]
+        # meta given with the call takes precedence
+        call_meta.update(meta or {})
+        if call_meta:
+            request["meta"] = call_meta
+        return request
+
+    def _next_cb_id(self) -> int:
+        with self._lock:
+            self._cb_id += 1
+            return self._cb_id
+
+    def request(self, name: str, data: Dict[str, Any], meta: Optional[Dict[str, str]]) -> Any:
+        request = self._new_call(name, meta)
+        request["cb_id"] = cb_id = self._next_cb_id()
+        request["data"] = data
+        future: Future = Future()
+        with self._lock:
+            self._callbacks[cb_id] = future
+        self.send(request)
+        return future.result(self._timeout)
+
+    def oneway(self, name: str, data: Dict[str, Any], meta: Optional[Dict[str, str]]):
+        request = self._new_call(name, meta)
+        request["data"] = data
+        self.send(request)
+
+    def stream(self, name: str, data: Dict[str, Any], meta: Optional[Dict[str, str]], item_type: Any) -> Stream:
+        request = self._new_call(name, meta)
+        request["cb_id"] = cb_id = self._next_cb_id()
+        request["data"] = data
+        s = Stream(self, cb_id, item_type)
+        with self._lock:
+            self._streams[cb_id] = s
+        self.send(request)
+        return s
+
+    def end_stream(self, cb_id: int):
+        with self._lock:
+            self._streams.pop(cb_id, None)
+
+    def end_producer(self, cb_id: int):
+        with self._lock:
+            self._producers.pop(cb_id, None)
+
+    def new_producer(self, cb_id: int) -> StreamSender:
+        p = StreamSender(self, cb_id)
+        with self._lock:
+            self._producers[cb_id] = p
+        return p
+
+    def _read(self):
+        while True:
+            try:
+                data = self._transport.recv()
+            except OSError:
+                data = None
+            if data is None:
+                self._stop(AngoError("connectionClosed", "connection closed"))
+                return
+            try:
+                self._handle(json.loads(data) if isinstance(data, str) else _MsgpackDecoder(data).decode())
+            except (ValueError, KeyError, TypeError, AttributeError):
+                # invalid message, the connection can't be trusted anymore
+                self._transport.close()
+                self._stop(AngoError("connectionClosed", "protocol error"))
+                return
+
+    def _handle(self, message: Dict[str, Any]):
+        kind = message.get("type")
+        cb_id = message.get("cb_id")
+        if kind == "batch":
+            for m in message.get("data") or []:
+                self._handle(m)
+        elif kind == "res":
+            with self._lock:
+                future = self._callbacks.pop(cb_id, None)
+            if future is None:
+                return
+            if message.get("error") is not None:
+                future.set_exception(_error_from_json(message["error"]))
+            else:
+                future.set_result(message.get("data") or {})
+        elif kind == "item":
+            with self._lock:
+                s = self._streams.get(cb_id)
+            if s is not None:
+                s._put(message.get("data"))
+        elif kind == "end":
+            with self._lock:
+                s = self._streams.pop(cb_id, None)
+            if s is not None:
+                s._put(_error_from_json(message["error"]) if message.get("error") is not None else Stream._end)
+        elif kind == "credit":
+            with self._lock:
+                p = self._producers.get(cb_id)
+            if p is not None:
+                p._grant(message["data"]["credit"])
+        elif kind == "cancel":
+            with self._lock:
+                p = self._producers.pop(cb_id, None)
+            if p is not None:
+                p._cancel()
+        elif kind == "goingAway":
+            self._going_away = True
+            if self._on_going_away is not None:
+                self._on_going_away()
+        elif kind == "req":
+            # handlers run in their own thread, so they can call the server and receive stream credit
+            threading.Thread(target=self._dispatch, args=(self, message), daemon=True).start()
+
+    def respond(self, message: Dict[str, Any], fn: Callable[[], Any]):
+        """Calls fn for an incoming request and sends the response, unless the procedure is oneway."""
+        cb_id = message.get("cb_id")
+        try:
+            result = fn()
+        except NotImplementedError:
+            error: Optional[Dict[str, str]] = {"type": "procedureUnavailable", "message": "procedure unavailable"}
+            result = None
+        except AngoError as err:
+            error = {"type": err.type, "message": err.message}
+            result = None
+        except Exception as err:
+            error = {"type": "errorReturned", "message": str(err)}
+            result = None
+        else:
+            error = None
+        if cb_id is None:
+            return
+        if error is not None:
+            self.send({"type": "res", "cb_id": cb_id, "error": error})
+        else:
+            self.send({"type": "res", "cb_id": cb_id, "data": result if result is not None else {}})
+
+    def _stop(self, err: AngoError):
+        with self._lock:
+            if self._stop_error is not None:
+                return
+            self._stop_error = err
+            callbacks, self._callbacks = self._callbacks, {}
+            streams, self._streams = self._streams, {}
+            producers, self._producers = self._producers, {}
+        for future in callbacks.values():
+            future.set_exception(err)
+        for s in streams.values():
+            s._put(err)
+        for p in producers.values():
+            p._cancel()
+
+
+def _available_procedures(server_signatures: str) -> set:
+    """Returns the server procedures with the same signature in server_signatures.
+    A signature set is formatted as "service=name;server=proc:sig,...;client=proc:sig,..."."""
+
+    def parse(signature_set: str) -> Dict[str, Dict[str, str]]:
+        sections: Dict[str, Dict[str, str]] = {"server": {}, "client": {}}
+        for part in signature_set.split(";"):
+            section, _, entries = part.partition("=")
+            if section not in sections or not entries:
+                continue
+            for entry in entries.split(","):
+                name, _, signature = entry.partition(":")
+                sections[section][name] = signature
+        return sections
+
+    own = parse(SIGNATURES)["server"]
+    other = parse(server_signatures)["server"]
+    return {name for name, signature in own.items() if other.get(name) == signature}
+
+
+class {{.Service.CapitalizedName}}Handler:
+    """Implements the client procedures, called by the server. Override the methods for the procedures that this
+    client handles, the others reply procedureUnavailable. A method returns the result dataclass (or a dict),
+    raising an exception sends the error to the server."""
+{{range .Service.PyProcedures "client"}}
+    def {{.Name}}(self{{.PyArgs}}{{if .Stream}}, stream: StreamSender{{end}}, options: HandlerOptions){{if .Stream}} -> None{{else if .Oneway}} -> None{{else if .Rets}} -> "{{.PyRetsName}}"{{else}} -> None{{end}}:
+        raise NotImplementedError
+{{else}}
+    pass
+{{end}}
+
+def _dispatch(handler: {{.Service.CapitalizedName}}Handler, conn: _Connection, message: Dict[str, Any]):
+    data = message.get("data") or {}
+    options = HandlerOptions(meta=message.get("meta") or {})
+    name = message.get("procedure")
+{{range .Service.PyProcedures "client"}}
+    if name == "{{.Name}}":
+{{- if .Stream}}
+        sender = conn.new_producer(message["cb_id"])
+        try:
+            handler.{{.Name}}({{.PyCallArgs}}sender, options)
+        except NotImplementedError:
+            sender.end("procedure unavailable")
+        except Exception as err:
+            sender.end(str(err))
+        return
+{{- else}}
+        conn.respond(message, lambda: handler.{{.Name}}({{.PyCallArgs}}options))
+        return
+{{- end}}
+{{- end}}
+    conn.respond(message, lambda: _raise(NotImplementedError()))
+
+
+def _raise(err: Exception):
+    raise err
+
+
+class {{.Service.CapitalizedName}}Client:
+    """Connection to the {{.Service.Name}} service, with a method for every server procedure.
+
+    url is the websocket url, e.g. "ws://localhost:8080/websocket-ango-{{.Service.Name}}".
+    handler implements the client procedures, it's optional when the client doesn't handle any.
+    transport replaces the builtin websocket client, trace_context is called with the procedure name for every call
+    and may return {"traceparent": ..., "baggage": ...} (W3C trace context) to send along with the request.
+    on_going_away is called when the server is shutting down, new calls fail with AngoError goingAway.
+    timeout is the maximum number of seconds to wait for a response, None waits forever.
+    codec is "json" or "msgpack", the server may answer that it uses JSON.
+
+    Every method accepts meta as keyword argument, e.g. client.add(1, 2, meta={"locale": "nl"}).
+    """
+
+    def __init__(self, url: str, handler: Optional[{{.Service.CapitalizedName}}Handler] = None, *,
+                 transport: Any = None, trace_context: Optional[Callable[[str], Optional[Dict[str, str]]]] = None,
+                 on_going_away: Optional[Callable[[], None]] = None, timeout: Optional[float] = None,
+                 codec: str = "json"):
+        if codec not in ("json", "msgpack"):
+            raise ValueError("unknown codec %r" % codec)
+        if transport is None:
+            transport = WebSocket(url)
+        if handler is None:
+            handler = {{.Service.CapitalizedName}}Handler()
+        self._conn = _Connection(transport, functools.partial(_dispatch, handler), trace_context, on_going_away, timeout,
+                                 codec)
+
+    @property
+    def codec(self) -> str:
+        """The codec used by the connection, "json" or "msgpack"."""
+        return self._conn.codec
+
+    def close(self):
+        """Closes the connection, pending calls and streams fail with AngoError connectionClosed."""
+        self._conn.close()
+
+    def __enter__(self):
+        return self
+
+    def __exit__(self, *exc):
+        self.close()
+
+    # PROCEDURES, as defined in .ango file
+{{range .Service.PyProcedures "server"}}
+    def {{.Name}}(self{{.PyArgs}}, *, meta: Optional[Dict[str, str]] = None){{if .Stream}} -> Stream{{else if .Subscribe}} -> Subscription{{else if .Oneway}} -> None{{else if .Rets}} -> "{{.PyRetsName}}"{{else}} -> None{{end}}:
+{{- range .Args}}{{if .IsNumber}}
+        _check_int("{{.Name}}", {{.PyName}}, {{.NumberMin}}, {{.NumberMax}})
+{{- end}}{{end}}
+{{- if .Stream}}
+        return self._conn.stream("{{.Name}}", {{.PyArgsData}}, meta, "{{.PyRetsName}}")
+{{- else if .Subscribe}}
+        stream = self._conn.stream("{{.Name}}", {{.PyArgsData}}, meta, "{{.PyRetsName}}")
+        return Subscription(stream, {{if eq (len .Rets) 1}}"{{(index .Rets 0).PyName}}"{{else}}None{{end}})
+{{- else if .Oneway}}
+        self._conn.oneway("{{.Name}}", {{.PyArgsData}}, meta)
+{{- else if .Rets}}
+        return _from_json("{{.PyRetsName}}", self._conn.request("{{.Name}}", {{.PyArgsData}}, meta))
+{{- else}}
+        self._conn.request("{{.Name}}", {{.PyArgsData}}, meta)
+{{- end}}
+{{end}}
@@ -0,0 +1,45 @@
+// WARNING This is generated code by the ango tool (github.com/GeertJohan/ango)
+// DO NOT EDIT unless you know what you're doing!
+// The generated code requires Go 1.21 or later.
+
+package {{.PackageName}}
+
+import (
+	"bytes"
+	"testing"
+)
+
+// msgpackNested returns a map with key, holding depth nested arrays around a nil.
+func msgpackNested(key string, depth int) []byte {
+	frame := []byte{0x81, 0xa0 | byte(len(key))}
+	frame = append(frame, key...)
+	frame = append(frame, bytes.Repeat([]byte{0x91}, depth)...)
+	return append(frame, 0xc0)
+}
+
+func TestMsgpackMaxDepth(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		v    func() interface{}
+	}{
+		// skipped into a json.RawMessage
+		{"raw", "data", func() interface{} { return &angoInMsg{} }},
+		// skipped as unknown field
+		{"unknown field", "unknown", func() interface{} { return &angoInMsg{} }},
+		// decoded into interface{} values
+		{"interface", "data", func() interface{} { return new(interface{}) }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := msgpackCodec{}.unmarshal(msgpackNested(test.key, 100), test.v())
+			if err != nil {
+				t.Fatalf("nested value within the limit: %v", err)
+			}
+			err = msgpackCodec{}.unmarshal(msgpackNested(test.key, msgpackMaxDepth+1), test.v())
+			if err != errMsgpackDepth {
+				t.Fatalf("nested value beyond the limit: got error %v, expected %v", err, errMsgpackDepth)
+			}
+		})
+	}
+}
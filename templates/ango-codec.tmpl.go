@@ -0,0 +1,877 @@
+// WARNING This is generated code by the ango tool (github.com/GeertJohan/ango)
+// DO NOT EDIT unless you know what you're doing!
+// The generated code requires Go 1.21 or later.
+
+package {{.PackageName}}
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// Codec names, as negotiated during the version handshake.
+const (
+	// CodecJSON encodes messages as JSON text messages. It is the default codec.
+	CodecJSON = "json"
+
+	// CodecMessagePack encodes messages as MessagePack (https://msgpack.org) binary messages.
+	CodecMessagePack = "msgpack"
+)
+
+// codec encodes and decodes the messages on a connection.
+type codec interface {
+	// marshal encodes v into a single message.
+	marshal(v interface{}) ([]byte, error)
+
+	// unmarshal decodes data into v, which must be a pointer.
+	unmarshal(data []byte, v interface{}) error
+
+	// messageType returns the websocket message type used for encoded messages.
+	messageType() int
+}
+
+// codecs holds all supported codecs by name.
+var codecs = map[string]codec{
+	CodecJSON:        jsonCodec{},
+	CodecMessagePack: msgpackCodec{},
+}
+
+// negotiateCodec selects the codec for a connection.
+// offer is the comma separated list of codecs the client sent after the version string, in order of preference.
+// The first supported codec is used, JSON is used when none is supported or the server is JSONOnly.
+func (server *Server) negotiateCodec(offer string) string {
+	if server.JSONOnly {
+		return CodecJSON
+	}
+	for _, name := range strings.Split(offer, ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := codecs[name]; ok {
+			return name
+		}
+	}
+	return CodecJSON
+}
+
+// jsonCodec encodes messages with encoding/json.
+// The generated MarshalJSON and UnmarshalJSON methods are called directly, encoding/json would
+// validate and copy their output or scan their input an extra time.
+type jsonCodec struct{}
+
+func (jsonCodec) marshal(v interface{}) ([]byte, error) {
+	if m, ok := v.(json.Marshaler); ok {
+		return m.MarshalJSON()
+	}
+	return json.Marshal(v)
+}
+
+func (jsonCodec) unmarshal(data []byte, v interface{}) error {
+	if u, ok := v.(json.Unmarshaler); ok {
+		return u.UnmarshalJSON(data)
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) messageType() int {
+	return websocket.TextMessage
+}
+
+// msgpackCodec encodes messages as MessagePack.
+// Values are mapped the same way as encoding/json maps them: struct fields use the name from the json tag,
+// and the omitempty and "-" tag options are respected.
+// Byte slices are encoded as binary values. Where encoding/json expects a base64 string,
+// a binary value and a base64 string are both accepted, so clients can send either.
+// A json.RawMessage field holds the undecoded MessagePack bytes for that field.
+type msgpackCodec struct{}
+
+var (
+	errMsgpackShort    = errors.New("msgpack: unexpected end of data")
+	errMsgpackTrailing = errors.New("msgpack: trailing data after value")
+	errMsgpackPointer  = errors.New("msgpack: unmarshal requires a non-nil pointer")
+	errMsgpackDepth    = errors.New("msgpack: exceeded max depth")
+)
+
+// msgpackMaxDepth is the maximum nesting of decoded values, the same limit as encoding/json.
+// Without it, a message of nested arrays would exhaust the stack.
+const msgpackMaxDepth = 10000
+
+// rawMessageType is the reflect.Type for json.RawMessage
+var rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+
+func (msgpackCodec) marshal(v interface{}) ([]byte, error) {
+	e := &msgpackEncoder{}
+	err := e.encode(reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+func (msgpackCodec) unmarshal(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errMsgpackPointer
+	}
+	d := &msgpackDecoder{data: data}
+	err := d.decode(rv.Elem())
+	if err != nil {
+		return err
+	}
+	if d.pos != len(d.data) {
+		return errMsgpackTrailing
+	}
+	return nil
+}
+
+func (msgpackCodec) messageType() int {
+	return websocket.BinaryMessage
+}
+
+// msgpackField describes a struct field as it is encoded.
+type msgpackField struct {
+	name      string
+	index     int
+	omitEmpty bool
+}
+
+// msgpackFieldCache holds the []msgpackField for struct types, by reflect.Type.
+var msgpackFieldCache sync.Map
+
+// msgpackFields returns the encoded fields for struct type t.
+func msgpackFields(t reflect.Type) []msgpackField {
+	if fields, ok := msgpackFieldCache.Load(t); ok {
+		return fields.([]msgpackField)
+	}
+	fields := make([]msgpackField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			// unexported field
+			continue
+		}
+		field := msgpackField{name: sf.Name, index: i}
+		if tag := sf.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			parts := strings.Split(tag, ",")
+			if parts[0] != "" {
+				field.name = parts[0]
+			}
+			for _, option := range parts[1:] {
+				if option == "omitempty" {
+					field.omitEmpty = true
+				}
+			}
+		}
+		fields = append(fields, field)
+	}
+	msgpackFieldCache.Store(t, fields)
+	return fields
+}
+
+// isEmptyValue reports whether v is empty, following the omitempty rules of encoding/json.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// msgpackEncoder appends MessagePack encoded values to buf.
+type msgpackEncoder struct {
+	buf []byte
+}
+
+func (e *msgpackEncoder) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		e.buf = append(e.buf, 0xc0)
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			e.buf = append(e.buf, 0xc0)
+			return nil
+		}
+		return e.encode(v.Elem())
+	case reflect.Bool:
+		if v.Bool() {
+			e.buf = append(e.buf, 0xc3)
+		} else {
+			e.buf = append(e.buf, 0xc2)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.encodeInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.encodeUint(v.Uint())
+	case reflect.Float32:
+		e.buf = append(e.buf, 0xca)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		e.buf = append(e.buf, 0xcb)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, math.Float64bits(v.Float()))
+	case reflect.String:
+		e.encodeString(v.String())
+	case reflect.Slice:
+		if v.IsNil() {
+			e.buf = append(e.buf, 0xc0)
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			e.encodeBinary(v.Bytes())
+			return nil
+		}
+		fallthrough
+	case reflect.Array:
+		e.encodeHeader(v.Len(), 0x90, 0xdc, 0xdd)
+		for i := 0; i < v.Len(); i++ {
+			err := e.encode(v.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			e.buf = append(e.buf, 0xc0)
+			return nil
+		}
+		e.encodeHeader(v.Len(), 0x80, 0xde, 0xdf)
+		iter := v.MapRange()
+		for iter.Next() {
+			err := e.encode(iter.Key())
+			if err != nil {
+				return err
+			}
+			err = e.encode(iter.Value())
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		fields := msgpackFields(v.Type())
+		n := 0
+		for _, f := range fields {
+			if !f.omitEmpty || !isEmptyValue(v.Field(f.index)) {
+				n++
+			}
+		}
+		e.encodeHeader(n, 0x80, 0xde, 0xdf)
+		for _, f := range fields {
+			fv := v.Field(f.index)
+			if f.omitEmpty && isEmptyValue(fv) {
+				continue
+			}
+			e.encodeString(f.name)
+			err := e.encode(fv)
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("msgpack: unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// encodeHeader writes the header for a string, array or map with n elements.
+// fix is the fix-type prefix, or zero when the type has no fix variant for n.
+func (e *msgpackEncoder) encodeHeader(n int, fix byte, code16 byte, code32 byte) {
+	switch {
+	case fix != 0 && n < 16:
+		e.buf = append(e.buf, fix|byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, code16)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, code32)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	}
+}
+
+func (e *msgpackEncoder) encodeBinary(b []byte) {
+	switch n := len(b); {
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xc5)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, 0xc6)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	}
+	e.buf = append(e.buf, b...)
+}
+
+func (e *msgpackEncoder) encodeString(s string) {
+	switch n := len(s); {
+	case n < 32:
+		e.buf = append(e.buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xda)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, 0xdb)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	}
+	e.buf = append(e.buf, s...)
+}
+
+func (e *msgpackEncoder) encodeInt(i int64) {
+	switch {
+	case i >= 0:
+		e.encodeUint(uint64(i))
+	case i >= -32:
+		e.buf = append(e.buf, byte(i))
+	case i >= math.MinInt8:
+		e.buf = append(e.buf, 0xd0, byte(i))
+	case i >= math.MinInt16:
+		e.buf = append(e.buf, 0xd1)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(i))
+	case i >= math.MinInt32:
+		e.buf = append(e.buf, 0xd2)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(i))
+	default:
+		e.buf = append(e.buf, 0xd3)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(i))
+	}
+}
+
+func (e *msgpackEncoder) encodeUint(u uint64) {
+	switch {
+	case u <= 0x7f:
+		e.buf = append(e.buf, byte(u))
+	case u <= math.MaxUint8:
+		e.buf = append(e.buf, 0xcc, byte(u))
+	case u <= math.MaxUint16:
+		e.buf = append(e.buf, 0xcd)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(u))
+	case u <= math.MaxUint32:
+		e.buf = append(e.buf, 0xce)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(u))
+	default:
+		e.buf = append(e.buf, 0xcf)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, u)
+	}
+}
+
+// msgpackDecoder decodes MessagePack values from data.
+type msgpackDecoder struct {
+	data  []byte
+	pos   int
+	depth int // nesting of skip, decode and decodeInterface calls
+}
+
+// enter increments the depth for a nested value, it returns errMsgpackDepth when the depth exceeds msgpackMaxDepth.
+// Every call to enter must be followed by a call to leave.
+func (d *msgpackDecoder) enter() error {
+	d.depth++
+	if d.depth > msgpackMaxDepth {
+		return errMsgpackDepth
+	}
+	return nil
+}
+
+// leave decrements the depth after a nested value was decoded.
+func (d *msgpackDecoder) leave() {
+	d.depth--
+}
+
+func (d *msgpackDecoder) peek() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, errMsgpackShort
+	}
+	return d.data[d.pos], nil
+}
+
+func (d *msgpackDecoder) read(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.pos < n {
+		return nil, errMsgpackShort
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// readUint reads an unsigned big endian integer of n bytes.
+func (d *msgpackDecoder) readUint(n int) (uint64, error) {
+	b, err := d.read(n)
+	if err != nil {
+		return 0, err
+	}
+	var u uint64
+	for _, c := range b {
+		u = u<<8 | uint64(c)
+	}
+	return u, nil
+}
+
+// msgpackKind is the kind of value that starts with the given type byte.
+type msgpackKind int
+
+const (
+	msgpackNil msgpackKind = iota
+	msgpackBool
+	msgpackInt
+	msgpackUint
+	msgpackFloat
+	msgpackString
+	msgpackBinary
+	msgpackArray
+	msgpackMap
+	msgpackExt
+)
+
+// readHeader reads a type byte and any length or value that follows it.
+// For int, uint and bool the value is returned in n (as two's complement for int), for float the bits.
+// For string, binary, array, map and ext the length is returned in n.
+func (d *msgpackDecoder) readHeader() (kind msgpackKind, n uint64, err error) {
+	b, err := d.read(1)
+	if err != nil {
+		return 0, 0, err
+	}
+	c := b[0]
+	switch {
+	case c <= 0x7f:
+		return msgpackUint, uint64(c), nil
+	case c >= 0xe0:
+		return msgpackInt, uint64(int64(int8(c))), nil
+	case c&0xf0 == 0x80:
+		return msgpackMap, uint64(c & 0x0f), nil
+	case c&0xf0 == 0x90:
+		return msgpackArray, uint64(c & 0x0f), nil
+	case c&0xe0 == 0xa0:
+		return msgpackString, uint64(c & 0x1f), nil
+	}
+	switch c {
+	case 0xc0:
+		return msgpackNil, 0, nil
+	case 0xc2:
+		return msgpackBool, 0, nil
+	case 0xc3:
+		return msgpackBool, 1, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err = d.readUint(1 << (c - 0xc4))
+		return msgpackBinary, n, err
+	case 0xc7, 0xc8, 0xc9:
+		n, err = d.readUint(1 << (c - 0xc7))
+		if err == nil {
+			_, err = d.read(1) // ext type
+		}
+		return msgpackExt, n, err
+	case 0xca:
+		n, err = d.readUint(4)
+		return msgpackFloat, uint64(math.Float64bits(float64(math.Float32frombits(uint32(n))))), err
+	case 0xcb:
+		n, err = d.readUint(8)
+		return msgpackFloat, n, err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err = d.readUint(1 << (c - 0xcc))
+		return msgpackUint, n, err
+	case 0xd0:
+		n, err = d.readUint(1)
+		return msgpackInt, uint64(int64(int8(n))), err
+	case 0xd1:
+		n, err = d.readUint(2)
+		return msgpackInt, uint64(int64(int16(n))), err
+	case 0xd2:
+		n, err = d.readUint(4)
+		return msgpackInt, uint64(int64(int32(n))), err
+	case 0xd3:
+		n, err = d.readUint(8)
+		return msgpackInt, n, err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		_, err = d.read(1) // ext type
+		return msgpackExt, 1 << (c - 0xd4), err
+	case 0xd9, 0xda, 0xdb:
+		n, err = d.readUint(1 << (c - 0xd9))
+		return msgpackString, n, err
+	case 0xdc, 0xdd:
+		n, err = d.readUint(2 << (c - 0xdc))
+		return msgpackArray, n, err
+	case 0xde, 0xdf:
+		n, err = d.readUint(2 << (c - 0xde))
+		return msgpackMap, n, err
+	}
+	return 0, 0, fmt.Errorf("msgpack: invalid type byte 0x%02x", c)
+}
+
+// skip skips the next value.
+func (d *msgpackDecoder) skip() error {
+	err := d.enter()
+	defer d.leave()
+	if err != nil {
+		return err
+	}
+	kind, n, err := d.readHeader()
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case msgpackString, msgpackBinary, msgpackExt:
+		_, err = d.read(int(n))
+		return err
+	case msgpackArray, msgpackMap:
+		if kind == msgpackMap {
+			n *= 2
+		}
+		for i := uint64(0); i < n; i++ {
+			err = d.skip()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (d *msgpackDecoder) decode(v reflect.Value) error {
+	err := d.enter()
+	defer d.leave()
+	if err != nil {
+		return err
+	}
+	if v.Type() == rawMessageType {
+		start := d.pos
+		c, err := d.peek()
+		if err != nil {
+			return err
+		}
+		err = d.skip()
+		if err != nil {
+			return err
+		}
+		if c == 0xc0 {
+			v.SetBytes(nil)
+		} else {
+			v.SetBytes(d.data[start:d.pos])
+		}
+		return nil
+	}
+
+	c, err := d.peek()
+	if err != nil {
+		return err
+	}
+	if c == 0xc0 {
+		// nil sets pointers, maps, slices and interfaces to nil, other values are left unchanged (like a json null)
+		d.pos++
+		switch v.Kind() {
+		case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decode(v.Elem())
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return fmt.Errorf("msgpack: cannot decode into %s", v.Type())
+		}
+		value, err := d.decodeInterface()
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(value))
+		return nil
+	}
+
+	start := d.pos
+	kind, n, err := d.readHeader()
+	if err != nil {
+		return err
+	}
+	mismatch := func() error {
+		return fmt.Errorf("msgpack: cannot decode value with type byte 0x%02x into %s", d.data[start], v.Type())
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if kind != msgpackBool {
+			return mismatch()
+		}
+		v.SetBool(n == 1)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		switch {
+		case kind == msgpackInt:
+			i = int64(n)
+		case kind == msgpackUint && n <= math.MaxInt64:
+			i = int64(n)
+		default:
+			return mismatch()
+		}
+		if v.OverflowInt(i) {
+			return fmt.Errorf("msgpack: value %d overflows %s", i, v.Type())
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if kind != msgpackUint {
+			return mismatch()
+		}
+		if v.OverflowUint(n) {
+			return fmt.Errorf("msgpack: value %d overflows %s", n, v.Type())
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		switch kind {
+		case msgpackFloat:
+			v.SetFloat(math.Float64frombits(n))
+		case msgpackInt:
+			v.SetFloat(float64(int64(n)))
+		case msgpackUint:
+			v.SetFloat(float64(n))
+		default:
+			return mismatch()
+		}
+	case reflect.String:
+		if kind != msgpackString {
+			return mismatch()
+		}
+		b, err := d.read(int(n))
+		if err != nil {
+			return err
+		}
+		v.SetString(string(b))
+	case reflect.Slice:
+		if kind == msgpackBinary && v.Type().Elem().Kind() == reflect.Uint8 {
+			b, err := d.read(int(n))
+			if err != nil {
+				return err
+			}
+			v.SetBytes(append([]byte(nil), b...))
+			return nil
+		}
+		if kind == msgpackString && v.Type().Elem().Kind() == reflect.Uint8 {
+			// base64, as with encoding/json
+			b, err := d.read(int(n))
+			if err != nil {
+				return err
+			}
+			decoded, err := base64.StdEncoding.DecodeString(string(b))
+			if err != nil {
+				return fmt.Errorf("msgpack: invalid base64 string for %s", v.Type())
+			}
+			v.SetBytes(decoded)
+			return nil
+		}
+		if kind != msgpackArray {
+			return mismatch()
+		}
+		if n > uint64(len(d.data)-d.pos) {
+			// every element takes at least one byte
+			return errMsgpackShort
+		}
+		v.Set(reflect.MakeSlice(v.Type(), int(n), int(n)))
+		for i := 0; i < int(n); i++ {
+			err = d.decode(v.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Array:
+		if kind != msgpackArray {
+			return mismatch()
+		}
+		for i := 0; i < int(n); i++ {
+			if i < v.Len() {
+				err = d.decode(v.Index(i))
+			} else {
+				err = d.skip()
+			}
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if kind != msgpackMap {
+			return mismatch()
+		}
+		t := v.Type()
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(t))
+		}
+		for i := uint64(0); i < n; i++ {
+			key := reflect.New(t.Key()).Elem()
+			err = d.decodeKey(key)
+			if err != nil {
+				return err
+			}
+			elem := reflect.New(t.Elem()).Elem()
+			err = d.decode(elem)
+			if err != nil {
+				return err
+			}
+			v.SetMapIndex(key, elem)
+		}
+	case reflect.Struct:
+		if kind != msgpackMap {
+			return mismatch()
+		}
+		fields := msgpackFields(v.Type())
+		for i := uint64(0); i < n; i++ {
+			var name string
+			err = d.decode(reflect.ValueOf(&name).Elem())
+			if err != nil {
+				return err
+			}
+			field := findMsgpackField(fields, name)
+			if field == nil {
+				err = d.skip()
+			} else {
+				err = d.decode(v.Field(field.index))
+			}
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("msgpack: unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// findMsgpackField finds the field with name, preferring an exact match over a case-insensitive match (like encoding/json).
+func findMsgpackField(fields []msgpackField, name string) *msgpackField {
+	var fold *msgpackField
+	for i := range fields {
+		if fields[i].name == name {
+			return &fields[i]
+		}
+		if fold == nil && strings.EqualFold(fields[i].name, name) {
+			fold = &fields[i]
+		}
+	}
+	return fold
+}
+
+// decodeKey decodes a map key. Integer keys are also accepted as string, because javascript object keys are always strings.
+func (d *msgpackDecoder) decodeKey(key reflect.Value) error {
+	c, err := d.peek()
+	if err != nil {
+		return err
+	}
+	isString := c&0xe0 == 0xa0 || (c >= 0xd9 && c <= 0xdb)
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if isString {
+			var s string
+			err = d.decode(reflect.ValueOf(&s).Elem())
+			if err != nil {
+				return err
+			}
+			i, err := strconv.ParseInt(s, 10, key.Type().Bits())
+			if err != nil {
+				return fmt.Errorf("msgpack: invalid map key %q for %s", s, key.Type())
+			}
+			key.SetInt(i)
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if isString {
+			var s string
+			err = d.decode(reflect.ValueOf(&s).Elem())
+			if err != nil {
+				return err
+			}
+			u, err := strconv.ParseUint(s, 10, key.Type().Bits())
+			if err != nil {
+				return fmt.Errorf("msgpack: invalid map key %q for %s", s, key.Type())
+			}
+			key.SetUint(u)
+			return nil
+		}
+	}
+	return d.decode(key)
+}
+
+// decodeInterface decodes the next value into the types encoding/json would use for an interface{}:
+// nil, bool, float64, string, []interface{} and map[string]interface{}. Binary values are returned as []byte.
+func (d *msgpackDecoder) decodeInterface() (interface{}, error) {
+	err := d.enter()
+	defer d.leave()
+	if err != nil {
+		return nil, err
+	}
+	kind, n, err := d.readHeader()
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case msgpackNil:
+		return nil, nil
+	case msgpackBool:
+		return n == 1, nil
+	case msgpackInt:
+		return float64(int64(n)), nil
+	case msgpackUint:
+		return float64(n), nil
+	case msgpackFloat:
+		return math.Float64frombits(n), nil
+	case msgpackString:
+		b, err := d.read(int(n))
+		return string(b), err
+	case msgpackBinary:
+		b, err := d.read(int(n))
+		return append([]byte(nil), b...), err
+	case msgpackArray:
+		if n > uint64(len(d.data)-d.pos) {
+			return nil, errMsgpackShort
+		}
+		values := make([]interface{}, n)
+		for i := range values {
+			values[i], err = d.decodeInterface()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	case msgpackMap:
+		values := make(map[string]interface{})
+		for i := uint64(0); i < n; i++ {
+			key, err := d.decodeInterface()
+			if err != nil {
+				return nil, err
+			}
+			value, err := d.decodeInterface()
+			if err != nil {
+				return nil, err
+			}
+			switch k := key.(type) {
+			case string:
+				values[k] = value
+			default:
+				values[fmt.Sprint(k)] = value
+			}
+		}
+		return values, nil
+	}
+	return nil, errors.New("msgpack: ext types are not supported")
+}
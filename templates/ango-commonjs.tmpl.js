@@ -0,0 +1,13 @@
+// WARNING This is generated code by the ango tool (github.com/GeertJohan/ango)
+// DO NOT EDIT unless you know what you're doing!
+
+// CommonJS module with the client for the {{.Service.Name}} service, e.g. for Node.js.
+//
+//	const { {{.Service.CapitalizedName}}Client } = require("./{{.Service.Name}}.gen.cjs");
+//	const client = new {{.Service.CapitalizedName}}Client({url: "ws://localhost:8080/websocket-ango-{{.Service.Name}}", WebSocket: require("ws")});
+
+"use strict";
+
+{{template "ango-core.tmpl.js" .}}
+
+module.exports = { {{.Service.CapitalizedName}}Client, AngoException };
@@ -0,0 +1,1327 @@
+// static constant globals for this generated service
+var serviceName = "{{.Service.Name}}";
+var protocolVersion = "{{.ProtocolVersion}}";
+// signature of every procedure, procedures with the same signature on both sides can be called when the versions differ
+var signatures = "{{.SignatureSet}}";
+
+// state enum
+var stateInit = 0;
+var stateRunning = 1;
+var stateStopped = 2;
+var stateGoingAway = 3;
+
+// errors
+var errStateStopped = "AngoError: state == stateStopped";
+var errVersionMismatch = "AngoError: version mismatch";
+var errGoingAway = "AngoError: server is going away";
+var errConnectionClosed = "AngoError: connection closed";
+// same error object as sent by the server for a procedure it can't handle
+var errProcedureUnavailable = {type: "procedureUnavailable", message: "procedure unavailable"};
+
+// number of stream items that may be sent before the receiving side must grant credit
+var streamWindow = 16;
+
+// exceptions
+var expMissingArgs = "AngoException: missing arguments";
+var expTooManyArgs = "AngoException: too many arguments";
+var expNotAFunction = "AngoException: not a function";
+var expWrongTypeArg = "AngoException: argument has wrong type";
+var expNumberOutOfRange = "AngoException: argument (number) is out of valid range";
+var expMissingProcedureHandler = "AngoException: missing procedure handler";
+var expWrongTypeError = "AngoException: error returned by procedure handler must be string";
+var expInvalidLogLevel = "AngoException: invalid log level";
+var expWrongTypeOptions = "AngoException: call options must be an object";
+var expWrongTypeMeta = "AngoException: meta values must be strings";
+var expInvalidCodec = "AngoException: unknown codec";
+var expProtocolError = "AngoException: protocol error";
+var expMissingUrl = "AngoException: missing url";
+var expMissingWebSocket = "AngoException: no WebSocket implementation";
+
+class AngoException extends Error {
+	constructor(message) {
+		super(message);
+		this.name = "AngoException";
+	}
+}
+
+// codecs
+// JSON is the default, MessagePack is used when set with the codec option and accepted by the server during the handshake.
+var codecJson = "json";
+var codecMessagePack = "msgpack";
+
+// log levels, messages below the current log level are not logged
+var logLevels = {
+	"debug": 0,
+	"info": 1,
+	"error": 2,
+	"none": 3,
+};
+
+// utf8 encoding for MessagePack strings, TextEncoder and TextDecoder are used when available
+var textEncoder = (typeof(TextEncoder) != "undefined") ? new TextEncoder() : null;
+var textDecoder = (typeof(TextDecoder) != "undefined") ? new TextDecoder() : null;
+function utf8Encode(s) {
+	if(textEncoder !== null) {
+		return textEncoder.encode(s);
+	}
+	var out = [];
+	for(var i = 0; i < s.length; i++) {
+		var c = s.charCodeAt(i);
+		if(c >= 0xd800 && c <= 0xdbff && i+1 < s.length) {
+			var c2 = s.charCodeAt(i+1);
+			if(c2 >= 0xdc00 && c2 <= 0xdfff) {
+				c = 0x10000 + ((c - 0xd800) << 10) + (c2 - 0xdc00);
+				i++;
+			}
+		}
+		if(c < 0x80) {
+			out.push(c);
+		} else if(c < 0x800) {
+			out.push(0xc0 | (c >> 6), 0x80 | (c & 0x3f));
+		} else if(c < 0x10000) {
+			out.push(0xe0 | (c >> 12), 0x80 | ((c >> 6) & 0x3f), 0x80 | (c & 0x3f));
+		} else {
+			out.push(0xf0 | (c >> 18), 0x80 | ((c >> 12) & 0x3f), 0x80 | ((c >> 6) & 0x3f), 0x80 | (c & 0x3f));
+		}
+	}
+	return out;
+}
+function utf8Decode(bytes) {
+	if(textDecoder !== null) {
+		return textDecoder.decode(bytes);
+	}
+	var s = "";
+	for(var i = 0; i < bytes.length; i++) {
+		var c = bytes[i];
+		if(c >= 0xf0) {
+			c = ((c & 0x07) << 18) | ((bytes[++i] & 0x3f) << 12) | ((bytes[++i] & 0x3f) << 6) | (bytes[++i] & 0x3f);
+			c -= 0x10000;
+			s += String.fromCharCode(0xd800 + (c >> 10), 0xdc00 + (c & 0x3ff));
+			continue;
+		}
+		if(c >= 0xe0) {
+			c = ((c & 0x0f) << 12) | ((bytes[++i] & 0x3f) << 6) | (bytes[++i] & 0x3f);
+		} else if(c >= 0xc0) {
+			c = ((c & 0x1f) << 6) | (bytes[++i] & 0x3f);
+		}
+		s += String.fromCharCode(c);
+	}
+	return s;
+}
+
+// skipKey returns true for object keys that are not sent: AngularJS uses keys starting with $$ for internal state.
+function skipKey(key) {
+	return typeof(key) == "string" && key.substr(0, 2) == "$$";
+}
+
+// jsonEncode encodes value as JSON, like angular.toJson does.
+function jsonEncode(value) {
+	return JSON.stringify(value, function(key, v) {
+		return skipKey(key) ? undefined : v;
+	});
+}
+
+// msgpackEncode encodes value as MessagePack (https://msgpack.org) and returns an ArrayBuffer.
+// Values are mapped like jsonEncode maps them: undefined and function values are skipped in objects,
+// and so are keys starting with $$.
+function msgpackEncode(value) {
+	var buf = new Uint8Array(256);
+	var view = new DataView(buf.buffer);
+	var pos = 0;
+	function ensure(n) {
+		if(pos + n <= buf.length) {
+			return;
+		}
+		var size = buf.length * 2;
+		while(size < pos + n) {
+			size *= 2;
+		}
+		var grown = new Uint8Array(size);
+		grown.set(buf);
+		buf = grown;
+		view = new DataView(buf.buffer);
+	}
+	// writeHeader writes the header for a string, binary, array or map with n elements
+	// fix is the fix-type prefix for n < fixMax, code8, code16 and code32 are the type bytes for larger n (0 when unavailable)
+	function writeHeader(n, fix, fixMax, code8, code16, code32) {
+		ensure(5);
+		if(n < fixMax) {
+			buf[pos++] = fix | n;
+		} else if(code8 != 0 && n <= 0xff) {
+			buf[pos++] = code8;
+			buf[pos++] = n;
+		} else if(n <= 0xffff) {
+			buf[pos++] = code16;
+			view.setUint16(pos, n);
+			pos += 2;
+		} else {
+			buf[pos++] = code32;
+			view.setUint32(pos, n);
+			pos += 4;
+		}
+	}
+	function writeBytes(bytes) {
+		ensure(bytes.length);
+		buf.set(bytes, pos);
+		pos += bytes.length;
+	}
+	function writeString(s) {
+		var bytes = utf8Encode(s);
+		writeHeader(bytes.length, 0xa0, 32, 0xd9, 0xda, 0xdb);
+		writeBytes(bytes);
+	}
+	function writeNumber(n) {
+		ensure(9);
+		if(Math.floor(n) !== n || Math.abs(n) > 9007199254740991) {
+			// not an integer, or no exact integer
+			buf[pos++] = 0xcb;
+			view.setFloat64(pos, n);
+			pos += 8;
+		} else if(n >= 0) {
+			if(n <= 0x7f) {
+				buf[pos++] = n;
+			} else if(n <= 0xff) {
+				buf[pos++] = 0xcc;
+				buf[pos++] = n;
+			} else if(n <= 0xffff) {
+				buf[pos++] = 0xcd;
+				view.setUint16(pos, n);
+				pos += 2;
+			} else if(n <= 0xffffffff) {
+				buf[pos++] = 0xce;
+				view.setUint32(pos, n);
+				pos += 4;
+			} else {
+				buf[pos++] = 0xcf;
+				view.setUint32(pos, Math.floor(n / 4294967296));
+				view.setUint32(pos+4, n >>> 0);
+				pos += 8;
+			}
+		} else {
+			if(n >= -32) {
+				buf[pos++] = n & 0xff;
+			} else if(n >= -128) {
+				buf[pos++] = 0xd0;
+				view.setInt8(pos, n);
+				pos += 1;
+			} else if(n >= -32768) {
+				buf[pos++] = 0xd1;
+				view.setInt16(pos, n);
+				pos += 2;
+			} else if(n >= -2147483648) {
+				buf[pos++] = 0xd2;
+				view.setInt32(pos, n);
+				pos += 4;
+			} else {
+				var hi = Math.floor(n / 4294967296);
+				buf[pos++] = 0xd3;
+				view.setInt32(pos, hi);
+				view.setUint32(pos+4, n - hi*4294967296);
+				pos += 8;
+			}
+		}
+	}
+	function write(value) {
+		if(value !== null && typeof(value) == "object" && typeof(value.toJSON) == "function") {
+			value = value.toJSON();
+		}
+		if(value === null || value === undefined || typeof(value) == "function") {
+			ensure(1);
+			buf[pos++] = 0xc0;
+			return;
+		}
+		switch(typeof(value)) {
+		case "boolean":
+			ensure(1);
+			buf[pos++] = value ? 0xc3 : 0xc2;
+			return;
+		case "number":
+			writeNumber(value);
+			return;
+		case "string":
+			writeString(value);
+			return;
+		}
+		if(value instanceof Uint8Array) {
+			writeHeader(value.length, 0, 0, 0xc4, 0xc5, 0xc6);
+			writeBytes(value);
+			return;
+		}
+		if(Array.isArray(value)) {
+			writeHeader(value.length, 0x90, 16, 0, 0xdc, 0xdd);
+			for(var i = 0; i < value.length; i++) {
+				write(value[i]);
+			}
+			return;
+		}
+		var keys = [];
+		for(var key in value) {
+			if(value.hasOwnProperty(key) && value[key] !== undefined && typeof(value[key]) != "function" && !skipKey(key)) {
+				keys.push(key);
+			}
+		}
+		writeHeader(keys.length, 0x80, 16, 0, 0xde, 0xdf);
+		for(var i = 0; i < keys.length; i++) {
+			writeString(keys[i]);
+			write(value[keys[i]]);
+		}
+	}
+	write(value);
+	return buf.buffer.slice(0, pos);
+}
+
+// base64Encode returns bytes as base64 string, the way []uint8 values are encoded in JSON.
+function base64Encode(bytes) {
+	var s = "";
+	for(var i = 0; i < bytes.length; i += 0x8000) {
+		s += String.fromCharCode.apply(null, bytes.subarray(i, i+0x8000));
+	}
+	return btoa(s);
+}
+
+// msgpackDecode decodes a MessagePack encoded ArrayBuffer.
+// Maps become objects and binary values become base64 strings, the same values as with JSON. It throws an AngoException on invalid data.
+function msgpackDecode(arrayBuffer) {
+	var bytes = new Uint8Array(arrayBuffer);
+	var view = new DataView(arrayBuffer);
+	var pos = 0;
+	function need(n) {
+		if(pos + n > bytes.length) {
+			throw new AngoException(expProtocolError);
+		}
+	}
+	function readUint(n) {
+		need(n);
+		var v;
+		switch(n) {
+		case 1:
+			v = bytes[pos];
+			break;
+		case 2:
+			v = view.getUint16(pos);
+			break;
+		case 4:
+			v = view.getUint32(pos);
+			break;
+		case 8:
+			v = view.getUint32(pos)*4294967296 + view.getUint32(pos+4);
+			break;
+		}
+		pos += n;
+		return v;
+	}
+	function readInt(n) {
+		need(n);
+		var v;
+		switch(n) {
+		case 1:
+			v = view.getInt8(pos);
+			break;
+		case 2:
+			v = view.getInt16(pos);
+			break;
+		case 4:
+			v = view.getInt32(pos);
+			break;
+		case 8:
+			v = view.getInt32(pos)*4294967296 + view.getUint32(pos+4);
+			break;
+		}
+		pos += n;
+		return v;
+	}
+	function readFloat(n) {
+		need(n);
+		var v = (n == 4) ? view.getFloat32(pos) : view.getFloat64(pos);
+		pos += n;
+		return v;
+	}
+	function readBytes(n) {
+		need(n);
+		var b = bytes.subarray(pos, pos+n);
+		pos += n;
+		return b;
+	}
+	function readArray(n) {
+		var a = [];
+		for(var i = 0; i < n; i++) {
+			a.push(read());
+		}
+		return a;
+	}
+	function readMap(n) {
+		var o = {};
+		for(var i = 0; i < n; i++) {
+			var key = read();
+			o[key] = read();
+		}
+		return o;
+	}
+	function read() {
+		need(1);
+		var c = bytes[pos++];
+		if(c <= 0x7f) {
+			return c;
+		}
+		if(c >= 0xe0) {
+			return c - 0x100;
+		}
+		switch(c & 0xf0) {
+		case 0x80:
+			return readMap(c & 0x0f);
+		case 0x90:
+			return readArray(c & 0x0f);
+		}
+		if((c & 0xe0) == 0xa0) {
+			return utf8Decode(readBytes(c & 0x1f));
+		}
+		switch(c) {
+		case 0xc0:
+			return null;
+		case 0xc2:
+			return false;
+		case 0xc3:
+			return true;
+		case 0xc4:
+			return base64Encode(readBytes(readUint(1)));
+		case 0xc5:
+			return base64Encode(readBytes(readUint(2)));
+		case 0xc6:
+			return base64Encode(readBytes(readUint(4)));
+		case 0xca:
+			return readFloat(4);
+		case 0xcb:
+			return readFloat(8);
+		case 0xcc:
+			return readUint(1);
+		case 0xcd:
+			return readUint(2);
+		case 0xce:
+			return readUint(4);
+		case 0xcf:
+			return readUint(8);
+		case 0xd0:
+			return readInt(1);
+		case 0xd1:
+			return readInt(2);
+		case 0xd2:
+			return readInt(4);
+		case 0xd3:
+			return readInt(8);
+		case 0xd9:
+			return utf8Decode(readBytes(readUint(1)));
+		case 0xda:
+			return utf8Decode(readBytes(readUint(2)));
+		case 0xdb:
+			return utf8Decode(readBytes(readUint(4)));
+		case 0xdc:
+			return readArray(readUint(2));
+		case 0xdd:
+			return readArray(readUint(4));
+		case 0xde:
+			return readMap(readUint(2));
+		case 0xdf:
+			return readMap(readUint(4));
+		}
+		// ext types are not used by ango
+		throw new AngoException(expProtocolError);
+	}
+	var value = read();
+	if(pos != bytes.length) {
+		throw new AngoException(expProtocolError);
+	}
+	return value;
+}
+
+// newEvent creates the event dispatched by the client, info is available as event.detail.
+function newEvent(type, info) {
+	if(typeof(CustomEvent) == "function") {
+		return new CustomEvent(type, {detail: info});
+	}
+	var ev = new Event(type);
+	ev.detail = info;
+	return ev;
+}
+
+// {{.Service.CapitalizedName}}Client is a connection to the {{.Service.Name}} service, it has a method for every server procedure.
+// The client doesn't depend on a framework: calls return Promises and events are dispatched on the client (an EventTarget).
+// It doesn't use window or document either, so it runs in Node.js as well: pass the ws package as WebSocket option when Node.js has no global WebSocket.
+//
+// options:
+//  - url: the websocket url, e.g. "ws://localhost:8080/websocket-ango-{{.Service.Name}}" (required)
+//  - handlers: an object with a function for every client procedure
+//  - WebSocket: the WebSocket constructor to use, defaults to the global WebSocket (e.g. require("ws") in Node.js before version 22)
+//  - codec: "json" (default) or "msgpack", MessagePack is used when the server accepts it
+//  - traceContext: function(procedureName) returning {traceparent, baggage} (W3C trace context) to send along with a request
+//  - logLevel: "debug", "info", "error" (default) or "none"
+//
+// events: "open", "error" (detail is the websocket error), "close", "wrongversion" and "goingaway".
+//
+// Calls made before the connection is set up are sent once the server accepted the handshake.
+class {{.Service.CapitalizedName}}Client extends EventTarget {
+	constructor(options) {
+		super();
+		var client = this;
+		if(typeof(options) != "object" || options === null || typeof(options.url) != "string") {
+			throw new AngoException(expMissingUrl);
+		}
+
+		// logging
+		var logLevel = logLevels.error;
+		if(options.logLevel !== undefined) {
+			if(!logLevels.hasOwnProperty(options.logLevel)) {
+				throw new AngoException(expInvalidLogLevel);
+			}
+			logLevel = logLevels[options.logLevel];
+		}
+		function logDebug() {
+			if(logLevel <= logLevels.debug) {
+				console.log.apply(console, arguments);
+			}
+		}
+		function logInfo() {
+			if(logLevel <= logLevels.info) {
+				console.info.apply(console, arguments);
+			}
+		}
+		function logError() {
+			if(logLevel <= logLevels.error) {
+				console.error.apply(console, arguments);
+			}
+		}
+
+		// codec, preferred by this client
+		var preferredCodec = codecJson;
+		if(options.codec !== undefined) {
+			if(options.codec != codecJson && options.codec != codecMessagePack) {
+				throw new AngoException(expInvalidCodec);
+			}
+			preferredCodec = options.codec;
+		}
+
+		// trace context
+		var traceContextFn = null;
+		if(options.traceContext !== undefined && options.traceContext !== null) {
+			if(typeof(options.traceContext) != "function") {
+				throw new AngoException(expNotAFunction);
+			}
+			traceContextFn = options.traceContext;
+		}
+		// setTraceContext replaces the traceContext function given in the options, null removes it.
+		client.setTraceContext = function(fn) {
+			if(fn !== null && typeof(fn) != "function") {
+				throw new AngoException(expNotAFunction);
+			}
+			traceContextFn = fn;
+		};
+
+		// handlers
+		var handlers = {};
+		if(options.handlers !== undefined) {
+			var requiredHandlers = [{{.Service.JsClientProceduresStringAry}}];
+			for (var i = 0; i < requiredHandlers.length; i++) {
+				if(typeof(options.handlers[requiredHandlers[i]]) != "function") {
+					throw new AngoException(expMissingProcedureHandler);
+				}
+			}
+			handlers = options.handlers;
+		}
+
+		var WebSocketImpl = options.WebSocket;
+		if(WebSocketImpl === undefined) {
+			WebSocketImpl = globalThis.WebSocket;
+		}
+		if(typeof(WebSocketImpl) != "function") {
+			throw new AngoException(expMissingWebSocket);
+		}
+
+		logInfo("Starting ango client "+serviceName+" with version "+protocolVersion);
+
+		// keep all pending requests here until they get responses
+		var callbacks = {};
+		// create a unique callback ID to map requests to responses
+		var currentCallbackID = 0;
+		// queue to hold sends when socket isn't open
+		var queue = [];
+		// items sent within the current tick, they are coalesced into a single batch message
+		var batch = [];
+		// consumer state for server stream procedure calls, by cb_id
+		var streams = {};
+		// producer state for client stream procedure calls, by cb_id
+		var producers = {};
+		// create our websocket object with the address to the websocket
+		// browsers negotiate compression (permessage-deflate) with the server and decompress frames transparently
+		var ws = new WebSocketImpl(options.url);
+		// communication state for this client
+		var state = stateInit;
+		// codec for this connection, set when the server accepted the handshake
+		var codec = codecJson;
+		// server procedures that can be called when the protocol versions differ, undefined when all procedures can be called
+		var available;
+		// binary messages (MessagePack) are received as ArrayBuffer
+		ws.binaryType = "arraybuffer";
+
+		ws.onopen = function(){
+			logDebug("websocket has been opened!");
+
+			// send version string, followed by the codecs in order of preference and the signatures
+			var codecs = codecJson;
+			if(preferredCodec != codecJson) {
+				codecs = preferredCodec+","+codecJson;
+			}
+			ws.send(protocolVersion+" "+codecs+" "+signatures);
+
+			client.dispatchEvent(newEvent("open"));
+		};
+
+		ws.onmessage = function(message) {
+			switch(state) {
+			case stateRunning:
+			case stateGoingAway:
+				handleMessage(decodeMessage(message.data));
+				break;
+			case stateInit:
+				// "good" can be followed by the codec the server accepted and, when the versions differ, the server's signatures
+				var handshake = message.data.split(" ");
+				switch(handshake[0]) {
+				case "good":
+					if(handshake.length > 1) {
+						codec = handshake[1];
+					}
+					if(handshake.length > 2) {
+						available = availableProcedures(handshake[2]);
+						logInfo("Connection initialized (version differs), incompatible procedures are unavailable");
+					}
+					logDebug("Connection initialized, using codec "+codec);
+					// set state
+					state = stateRunning;
+					// send queue
+					sendQueue();
+					break;
+				case "invalid":
+					logError("Cannot setup communication over websocket: invalid version string. (version mismatch between server and client?)");
+					// set state
+					state = stateStopped;
+					// error on all pending calls
+					errQueue(errVersionMismatch);
+					errCallbacks(errVersionMismatch);
+					errStreams(errVersionMismatch);
+					client.dispatchEvent(newEvent("wrongversion"));
+					break;
+				}
+				break
+			}
+		};
+
+		ws.onerror = function(err) {
+			logError("Error on websocket: ", err);
+			client.dispatchEvent(newEvent("error", err));
+			// some implementations (e.g. the WebSocket in Node.js 22) don't close after failing to connect
+			if(state == stateInit && ws.readyState != 1) {
+				closed();
+			}
+		}
+
+		ws.onclose = function() {
+			logInfo("ango websocket closed");
+			closed();
+		}
+
+		// closed fails everything that is pending, nothing can be sent or received anymore.
+		// This way a process (e.g. in Node.js) doesn't wait forever.
+		var closedDone = false;
+		function closed() {
+			if(closedDone) {
+				return;
+			}
+			closedDone = true;
+			state = stateStopped;
+			errQueue(errConnectionClosed);
+			errCallbacks(errConnectionClosed);
+			errStreams(errConnectionClosed);
+			client.dispatchEvent(newEvent("close"));
+		}
+
+		// close closes the connection. Streams are ended with an error.
+		client.close = function() {
+			ws.close();
+		};
+
+		// getCallbackID creates a new callback ID for a request
+		function getCallbackID() {
+			currentCallbackID += 1;
+			return currentCallbackID;
+		}
+
+		// sendQueue send all requests from queue
+		function sendQueue() {
+			if(queue.length > 0) {
+				logDebug("Going to send "+queue.length+" items from queue.");
+				sendItems(queue.splice(0, queue.length));
+			}
+		}
+
+		// sendBatch sends the items that were sent within the last tick
+		function sendBatch() {
+			var items = batch;
+			batch = [];
+			if(ws.readyState != 1) {
+				// the connection closed within this tick, WebSocket implementations like ws throw on send
+				queue = items.concat(queue);
+				errQueue(errConnectionClosed);
+				return;
+			}
+			sendItems(items);
+		}
+
+		// sendItems writes the messages for items to the websocket, multiple messages are written as a single batch message.
+		function sendItems(items) {
+			if(items.length == 1) {
+				ws.send(encodeMessage(items[0].message));
+			} else if(items.length > 1) {
+				var messages = [];
+				for(var i = 0; i < items.length; i++) {
+					messages.push(items[i].message);
+				}
+				ws.send(encodeMessage({
+					type: "batch",
+					data: messages,
+				}));
+			}
+
+			// resolve oneway requests, they are done when sent
+			for(var i = 0; i < items.length; i++) {
+				if(items[i].hasOwnProperty('oneway_resolve')) {
+					items[i].oneway_resolve({});
+				}
+			}
+		}
+
+		// sendItem sends the message for a queue item. Items sent within the same tick are coalesced into a single batch message.
+		// When the connection is not running yet, the item is placed on the queue.
+		function sendItem(item) {
+			if(ws.readyState == 1 && state != stateInit && queue.length == 0) {
+				if(batch.length == 0) {
+					setTimeout(sendBatch, 0);
+				}
+				batch.push(item);
+			} else {
+				// messages are encoded when sent, the codec is known after the handshake
+				queue.push(item);
+			}
+		}
+
+		// errQueue rejects all oneway requests from the queue and empties the queue.
+		// this is done when the connection could not be set up or broke.
+		function errQueue(err) {
+			var items = queue.splice(0, queue.length);
+			for(var i = 0; i < items.length; i++) {
+				if(items[i].hasOwnProperty('oneway_reject')) {
+					items[i].oneway_reject(err);
+				}
+			}
+		}
+
+		// errCallbacks rejects all pending requests
+		// this is done when the connection could not be set up or broke.
+		function errCallbacks(err) {
+			for(var cb_id in callbacks) {
+				if(callbacks.hasOwnProperty(cb_id)) {
+					callbacks[cb_id].reject(err);
+					delete callbacks[cb_id];
+				}
+			}
+		}
+
+		// availableProcedures returns the server procedures with the same signature in serverSignatures, as a set.
+		// A signature set is formatted as "service=name;server=proc:sig,...;client=proc:sig,...".
+		function availableProcedures(serverSignatures) {
+			var own = parseSignatures(signatures);
+			var other = parseSignatures(serverSignatures);
+			var procs = {};
+			for(var name in own.server) {
+				if(own.server.hasOwnProperty(name) && other.server[name] === own.server[name]) {
+					procs[name] = true;
+				}
+			}
+			return procs;
+		}
+
+		// parseSignatures parses a signature set into an object holding the signatures by section and procedure name.
+		function parseSignatures(set) {
+			var sections = {server: {}, client: {}};
+			var parts = set.split(";");
+			for(var i = 0; i < parts.length; i++) {
+				var section = parts[i].split("=");
+				if(section.length != 2 || !sections.hasOwnProperty(section[0]) || section[1] == "") {
+					continue;
+				}
+				var entries = section[1].split(",");
+				for(var j = 0; j < entries.length; j++) {
+					var entry = entries[j].split(":");
+					sections[section[0]][entry[0]] = entry[1];
+				}
+			}
+			return sections;
+		}
+
+		// procedureAvailable returns whether the server procedure can be called.
+		// Before the handshake all procedures are assumed available, the server rejects the unavailable ones.
+		function procedureAvailable(name) {
+			return available === undefined || available.hasOwnProperty(name);
+		}
+
+		// callMeta creates the meta object for an outgoing request from the trace context and the call options.
+		// Meta given in the call options takes precedence. Returns undefined when there is no meta.
+		function callMeta(name, options) {
+			var meta = {};
+			var hasMeta = false;
+			if(traceContextFn !== null) {
+				var tc = traceContextFn(name);
+				if(typeof(tc) == "object" && tc !== null) {
+					if(typeof(tc.traceparent) == "string") {
+						meta.traceparent = tc.traceparent;
+						hasMeta = true;
+					}
+					if(typeof(tc.baggage) == "string") {
+						meta.baggage = tc.baggage;
+						hasMeta = true;
+					}
+				}
+			}
+			if(typeof(options.meta) == "object" && options.meta !== null) {
+				for(var key in options.meta) {
+					if(options.meta.hasOwnProperty(key)) {
+						if(typeof(options.meta[key]) != "string") {
+							throw new AngoException(expWrongTypeMeta);
+						}
+						meta[key] = options.meta[key];
+						hasMeta = true;
+					}
+				}
+			}
+			if(!hasMeta) {
+				return undefined;
+			}
+			return meta;
+		}
+
+		// doRequest makes a new request
+		// it's either sent directly, or placed on queue (during startup)
+		// options is the optional trailing argument given to the procedure, e.g.: {meta: {locale: "nl"}}
+		function doRequest(name, oneway, data, options) {
+			if(state == stateStopped) {
+				return Promise.reject(errStateStopped);
+			}
+			if(state == stateGoingAway) {
+				return Promise.reject(errGoingAway);
+			}
+			if(!procedureAvailable(name)) {
+				return Promise.reject(errProcedureUnavailable);
+			}
+
+			// setup request
+			var request = {
+				type: "req",
+				procedure: name,
+				data: data,
+			}
+			var meta = callMeta(name, options);
+			if(meta !== undefined) {
+				request.meta = meta;
+			}
+
+			return new Promise(function(resolve, reject) {
+				var queueItem = {
+					message: request,
+				};
+
+				if(oneway) {
+					// oneway requests are resolved when the item is being sent
+					queueItem.oneway_resolve = resolve;
+					queueItem.oneway_reject = reject;
+				} else {
+					// setup callback to resolve the promise
+					var callbackID = getCallbackID();
+					callbacks[callbackID] = {
+						time: new Date(),
+						resolve: resolve,
+						reject: reject,
+					};
+					request.cb_id = callbackID;
+					logDebug('callback id: '+callbackID);
+				}
+
+				logDebug('Sending request', request);
+				sendItem(queueItem);
+			});
+		}
+
+		// sendMessage sends a message object, or places it on the queue when the connection is not running yet.
+		function sendMessage(messageObj) {
+			sendItem({
+				message: messageObj,
+			});
+		}
+
+		// encodeMessage encodes a message object with the codec for this connection.
+		function encodeMessage(messageObj) {
+			if(codec == codecMessagePack) {
+				return msgpackEncode(messageObj);
+			}
+			return jsonEncode(messageObj);
+		}
+
+		// decodeMessage decodes the data from an incomming websocket message, binary messages are MessagePack.
+		function decodeMessage(data) {
+			if(typeof(data) == "string") {
+				return JSON.parse(data);
+			}
+			return msgpackDecode(data);
+		}
+
+		// runFns calls all functions in fns with arg.
+		function runFns(fns, arg) {
+			for(var i = 0; i < fns.length; i++) {
+				fns[i](arg);
+			}
+		}
+
+		// doStream starts a call to a server stream procedure and returns the observable for the stream.
+		// The observable has the chainable methods onNext(fn), onError(fn) and onComplete(fn), and the method cancel().
+		function doStream(name, data, options) {
+			var callbackID = getCallbackID();
+			var s = {
+				nextFns: [],
+				errorFns: [],
+				completeFns: [],
+				consumed: 0,
+				ended: false,
+			};
+			var observable = {
+				onNext: function(fn) {
+					if(typeof(fn) != "function") {
+						throw new AngoException(expNotAFunction);
+					}
+					s.nextFns.push(fn);
+					return observable;
+				},
+				onError: function(fn) {
+					if(typeof(fn) != "function") {
+						throw new AngoException(expNotAFunction);
+					}
+					s.errorFns.push(fn);
+					return observable;
+				},
+				onComplete: function(fn) {
+					if(typeof(fn) != "function") {
+						throw new AngoException(expNotAFunction);
+					}
+					s.completeFns.push(fn);
+					return observable;
+				},
+				cancel: function() {
+					if(s.ended) {
+						return;
+					}
+					s.ended = true;
+					delete streams[callbackID];
+					sendMessage({
+						type: "cancel",
+						cb_id: callbackID,
+					});
+				},
+			};
+			s.observable = observable;
+
+			if(state == stateStopped || state == stateGoingAway || !procedureAvailable(name)) {
+				// error after the caller had the chance to register handlers
+				var err = (state == stateStopped) ? errStateStopped : (state == stateGoingAway) ? errGoingAway : errProcedureUnavailable;
+				s.ended = true;
+				setTimeout(function() {
+					runFns(s.errorFns, err);
+				}, 0);
+				return observable;
+			}
+
+			var request = {
+				type: "req",
+				procedure: name,
+				cb_id: callbackID,
+				data: data,
+			};
+			var meta = callMeta(name, options);
+			if(meta !== undefined) {
+				request.meta = meta;
+			}
+			streams[callbackID] = s;
+			logDebug('Starting stream', request);
+			sendMessage(request);
+			return observable;
+		}
+
+		// doSubscribe subscribes to a server subscribe procedure and returns the subscription.
+		// The subscription has the chainable methods onUpdate(fn), onError(fn) and onEnd(fn), and the method unsubscribe().
+		// The latest value is available as subscription.value.
+		// When the procedure has a single return value, that value is used; otherwise the value is an object holding the return values.
+		function doSubscribe(name, data, options, valueName) {
+			var updateFns = [];
+			var observable;
+			var subscription = {
+				value: undefined,
+				onUpdate: function(fn) {
+					if(typeof(fn) != "function") {
+						throw new AngoException(expNotAFunction);
+					}
+					updateFns.push(fn);
+					return subscription;
+				},
+				onError: function(fn) {
+					observable.onError(fn);
+					return subscription;
+				},
+				onEnd: function(fn) {
+					observable.onComplete(fn);
+					return subscription;
+				},
+				unsubscribe: function() {
+					observable.cancel();
+				},
+			};
+			observable = doStream(name, data, options).onNext(function(item) {
+				var value = (valueName === undefined) ? item : item[valueName];
+				subscription.value = value;
+				for(var i = 0; i < updateFns.length; i++) {
+					updateFns[i](value);
+				}
+			});
+			return subscription;
+		}
+
+		// errStreams ends all server streams with an error
+		// this is done when the connection could not be set up or broke.
+		function errStreams(err) {
+			for(var cb_id in streams) {
+				if(streams.hasOwnProperty(cb_id)) {
+					var s = streams[cb_id];
+					delete streams[cb_id];
+					s.ended = true;
+					runFns(s.errorFns, err);
+				}
+			}
+		}
+
+		// handleStreamItemMessage passes an incomming stream item to the observable, and grants credit to the server.
+		function handleStreamItemMessage(messageObj) {
+			var s = streams[messageObj.cb_id];
+			if(s === undefined) {
+				// stream was cancelled, ignore items that were already underway
+				return;
+			}
+			runFns(s.nextFns, messageObj.data);
+			s.consumed++;
+			if(s.consumed >= streamWindow/2) {
+				sendMessage({
+					type: "credit",
+					cb_id: messageObj.cb_id,
+					data: {credit: s.consumed},
+				});
+				s.consumed = 0;
+			}
+		}
+
+		// handleStreamEndMessage completes or errors a server stream
+		function handleStreamEndMessage(messageObj) {
+			var s = streams[messageObj.cb_id];
+			if(s === undefined) {
+				return;
+			}
+			delete streams[messageObj.cb_id];
+			s.ended = true;
+			if(typeof(messageObj.error) == "object" && messageObj.error != null) {
+				runFns(s.errorFns, messageObj.error);
+			} else {
+				runFns(s.completeFns);
+			}
+		}
+
+		// newProducer creates the producer for an incomming call to a client stream procedure.
+		// The handler receives producer.stream, with methods send(item), end([err]), onCancel(fn) and isCancelled().
+		// Items are sent as long as the server has granted credit, other items wait in producer.pending.
+		function newProducer(callbackID) {
+			var p = {
+				id: callbackID,
+				credit: streamWindow,
+				pending: [],
+				ending: false,
+				ended: false,
+				endError: undefined,
+				cancelled: false,
+				cancelFns: [],
+			};
+			p.stream = {
+				send: function(item) {
+					if(p.cancelled || p.ending) {
+						return false;
+					}
+					p.pending.push(item);
+					flushProducer(p);
+					return true;
+				},
+				end: function(err) {
+					if(p.cancelled || p.ending) {
+						return;
+					}
+					if(err !== undefined && typeof(err) != 'string') {
+						throw new AngoException(expWrongTypeError);
+					}
+					p.ending = true;
+					p.endError = err;
+					flushProducer(p);
+				},
+				onCancel: function(fn) {
+					if(typeof(fn) != "function") {
+						throw new AngoException(expNotAFunction);
+					}
+					p.cancelFns.push(fn);
+				},
+				isCancelled: function() {
+					return p.cancelled;
+				},
+			};
+			producers[callbackID] = p;
+			return p;
+		}
+
+		// flushProducer sends pending items while there is credit, and ends the stream when requested.
+		function flushProducer(p) {
+			if(p.ended) {
+				return;
+			}
+			while(p.credit > 0 && p.pending.length > 0) {
+				p.credit--;
+				sendMessage({
+					type: "item",
+					cb_id: p.id,
+					data: p.pending.shift(),
+				});
+			}
+			if(p.ending && p.pending.length == 0) {
+				p.ended = true;
+				delete producers[p.id];
+				var endMsg = {
+					type: "end",
+					cb_id: p.id,
+				};
+				if(p.endError !== undefined) {
+					endMsg.error = p.endError;
+				}
+				sendMessage(endMsg);
+			}
+		}
+
+		// handleStreamCancelMessage stops a client stream that was cancelled by the server
+		function handleStreamCancelMessage(messageObj) {
+			var p = producers[messageObj.cb_id];
+			if(p === undefined) {
+				return;
+			}
+			delete producers[messageObj.cb_id];
+			p.cancelled = true;
+			p.pending = [];
+			runFns(p.cancelFns);
+		}
+
+		// handleStreamCreditMessage allows a client stream to send more items
+		function handleStreamCreditMessage(messageObj) {
+			var p = producers[messageObj.cb_id];
+			if(p === undefined) {
+				return;
+			}
+			p.credit += messageObj.data.credit;
+			flushProducer(p);
+		}
+
+		function handleMessage(messageObj) {
+			logDebug("Received data from websocket: ", messageObj);
+
+			switch(messageObj.type) {
+			case "res":
+				handleResolveMessage(messageObj);
+				break;
+			case "req":
+				handleRequestMessage(messageObj);
+				break;
+			case "goingAway":
+				handleGoingAwayMessage();
+				break;
+			case "item":
+				handleStreamItemMessage(messageObj);
+				break;
+			case "end":
+				handleStreamEndMessage(messageObj);
+				break;
+			case "cancel":
+				handleStreamCancelMessage(messageObj);
+				break;
+			case "credit":
+				handleStreamCreditMessage(messageObj);
+				break;
+			case "batch":
+				// a batch holds multiple messages, e.g. the responses to a batch of requests
+				for(var i = 0; i < messageObj.data.length; i++) {
+					handleMessage(messageObj.data[i]);
+				}
+				break;
+			default:
+				logError("message with unknown type: ", messageObj);
+				break;
+			}
+		}
+
+		// handleResolveMessage resolves an outgoing request
+		function handleResolveMessage(messageObj) {
+			if(typeof(messageObj.cb_id) != 'number') {
+				throw new AngoException(expProtocolError);
+			}
+			if(!callbacks.hasOwnProperty(messageObj.cb_id)) {
+				logError("response for unknown callback id: ", messageObj.cb_id);
+				return;
+			}
+			var callback = callbacks[messageObj.cb_id];
+			delete callbacks[messageObj.cb_id];
+			if(typeof(messageObj.error) == "object" && messageObj.error != null) {
+				callback.reject(messageObj.error);
+			} else {
+				callback.resolve(messageObj.data);
+			}
+		}
+
+		// handleGoingAwayMessage handles the notice that the server is shutting down.
+		// Pending requests are still answered, new requests are rejected.
+		// The application can listen on the goingaway event to reconnect elsewhere.
+		function handleGoingAwayMessage() {
+			logInfo("Server is going away");
+			// set state
+			state = stateGoingAway;
+			// error on all queued requests, they will never be sent
+			errQueue(errGoingAway);
+			client.dispatchEvent(newEvent("goingaway"));
+		}
+
+		// handlerOptions creates the options object given as last argument to a procedure handler.
+		function handlerOptions(messageObj) {
+			var meta = {};
+			if(typeof(messageObj.meta) == "object" && messageObj.meta !== null) {
+				meta = messageObj.meta;
+			}
+			return {
+				meta: meta,
+			};
+		}
+
+		// handleRequestMessage handles an incomming request
+		function handleRequestMessage(messageObj) {
+			if(typeof(messageObj.procedure) != 'string') {
+				throw new AngoException(expProtocolError);
+			}
+			// the procedure can't be handled when no handlers were given
+			if(!handlers.hasOwnProperty(messageObj.procedure)) {
+				if(typeof(messageObj.cb_id) == 'number') {
+					sendMessage({
+						type: 'res',
+						cb_id: messageObj.cb_id,
+						error: errProcedureUnavailable,
+					});
+				}
+				return;
+			}
+			switch(messageObj.procedure) {
+				{{range .Service.ClientProcedures}}
+					case '{{.Name}}':
+						{{if .Stream}}
+							handlers.{{.Name}}({{.JsCallArgs}}{{if .Args}}, {{end}}newProducer(messageObj.cb_id).stream, handlerOptions(messageObj));
+						{{else}}
+						{{if not .Oneway}}var retsProm = {{end}}handlers.{{.Name}}({{.JsCallArgs}}{{if .Args}}, {{end}}handlerOptions(messageObj));
+						{{if not .Oneway}}
+							// handlers return the return values as object, or a promise (or other thenable) for them
+							Promise.resolve(retsProm).then(
+								function(rets) {
+									sendMessage({
+										type: 'res',
+										cb_id: messageObj.cb_id,
+										data: rets,
+									});
+								}, function(err) {
+									if(typeof(err) != 'string') {
+										throw new AngoException(expWrongTypeError);
+									}
+									sendMessage({
+										type: 'res',
+										cb_id: messageObj.cb_id,
+										error: err,
+									});
+								})
+						{{end}}
+						{{end}}
+					break;
+				{{end}}
+			}
+		}
+
+		// PROCEDURES, as defined in .ango file
+		{{range .Service.ServerProcedures}}
+		client.{{.Name}} = function( {{.JsArgs}} ) {
+			if(arguments.length > {{len .Args}}+1) {
+				throw new AngoException(expTooManyArgs);
+			}
+			if(arguments.length < {{len .Args}}) {
+				throw new AngoException(expMissingArgs);
+			}
+			// optional trailing options argument
+			var options = {};
+			if(arguments.length == {{len .Args}}+1) {
+				options = arguments[{{len .Args}}];
+				if(typeof(options) != 'object' || options === null) {
+					throw new AngoException(expWrongTypeOptions);
+				}
+			}
+			{{range .Args}}
+				if(typeof({{.Name}}) != '{{jsTypeOf .Type}}'){
+					throw new AngoException(expWrongTypeArg);
+				}
+
+				//++ TODO: move type checking to seperate functions; typeCheckInt(v), typeCheckMyType(v), typeCheckOtherType(v)
+				{{if .IsNumber}}
+					if({{.Name}} > {{.NumberMax}}) {
+						throw new AngoException(expNumberOutOfRange);
+					}
+					if({{.Name}} < {{.NumberMin}}) {
+						throw new AngoException(expNumberOutOfRange);
+					}
+				{{end}}
+			{{end}}
+			var data = {
+				{{range .Args}} "{{.Name}}": {{.Name}}, {{end}}
+			};
+			{{if .Stream}}
+				return doStream("{{.Name}}", data, options);
+			{{else if .Subscribe}}
+				return doSubscribe("{{.Name}}", data, options{{if eq (len .Rets) 1}}, "{{(index .Rets 0).Name}}"{{end}});
+			{{else}}
+				return doRequest("{{.Name}}", {{.Oneway}}, data, options);
+			{{end}}
+		};
+		{{end}}
+	}
+
+	// getServiceName returns the name of the service
+	getServiceName() {
+		return serviceName;
+	}
+
+	// getProtocolVersion returns the protocol version of this client
+	getProtocolVersion() {
+		return protocolVersion;
+	}
+}
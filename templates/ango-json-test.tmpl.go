@@ -0,0 +1,213 @@
+// WARNING This is generated code by the ango tool (github.com/GeertJohan/ango)
+// DO NOT EDIT unless you know what you're doing!
+// The generated code requires Go 1.21 or later.
+
+package {{.PackageName}}
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Reference import that is only used by generated code for definitions with custom types or params.
+var (
+	_ = bytes.Equal
+	_ = reflect.DeepEqual
+)
+
+// The tests in this file verify that the generated JSON methods produce the same output as encoding/json,
+// the benchmarks compare them with the reflection based encoding/json.
+// Each jsonReflect type has the same underlying type as the generated type, but not the generated methods.
+// The generated methods are called directly, like the JSON codec calls them.
+
+{{define "jsonTest"}}
+	type jsonReflect{{.Name}} {{.Type}}
+
+	func TestJSON{{.Name}}(t *testing.T) {
+		v := &{{.Sample}}
+		generated, generatedErr := v.MarshalJSON()
+		reflected, reflectErr := json.Marshal((*jsonReflect{{.Name}})(v))
+		if reflectErr != nil {
+			t.Skipf("type can't be encoded by encoding/json: %v", reflectErr)
+		}
+		if generatedErr != nil {
+			t.Fatalf("generated marshal: %v", generatedErr)
+		}
+		if !bytes.Equal(generated, reflected) {
+			t.Fatalf("generated JSON differs from encoding/json\ngenerated: %s\nreflect:   %s", generated, reflected)
+		}
+
+		decoded := &{{.Type}}{}
+		err := decoded.UnmarshalJSON(reflected)
+		if err != nil {
+			t.Fatalf("generated unmarshal: %v", err)
+		}
+		if !reflect.DeepEqual(decoded, v) {
+			t.Fatalf("generated unmarshal differs from original\ndecoded:  %#v\noriginal: %#v", decoded, v)
+		}
+	}
+
+	func BenchmarkJSONMarshal{{.Name}}(b *testing.B) {
+		v := &{{.Sample}}
+		if _, err := json.Marshal((*jsonReflect{{.Name}})(v)); err != nil {
+			b.Skipf("type can't be encoded by encoding/json: %v", err)
+		}
+		b.Run("generated", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := v.MarshalJSON(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run("reflect", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := json.Marshal((*jsonReflect{{.Name}})(v)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+
+	func BenchmarkJSONUnmarshal{{.Name}}(b *testing.B) {
+		data, err := json.Marshal((*jsonReflect{{.Name}})(&{{.Sample}}))
+		if err != nil {
+			b.Skipf("type can't be encoded by encoding/json: %v", err)
+		}
+		b.Run("generated", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := (&{{.Type}}{}).UnmarshalJSON(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run("reflect", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := json.Unmarshal(data, &jsonReflect{{.Name}}{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+{{end}}
+
+type jsonReflectOutMsg angoOutMsg
+type jsonReflectInMsg angoInMsg
+
+// jsonSampleOutMsg returns a batch message with the fields of the message envelope set
+func jsonSampleOutMsg() *angoOutMsg {
+	return &angoOutMsg{
+		Type: msgTypeBatch,
+		Data: []*angoOutMsg{
+			{Type: "req", Procedure: "ango", CallbackID: 42, Data: &angoCreditData{Credit: 8}, Meta: map[string]string{"b": "<ango>", "a": "ango"}},
+			{Type: "res", CallbackID: 42, Error: &angoOutError{Type: "errorReturned", Message: "ango \u2028"}},
+		},
+	}
+}
+
+func TestJSONMessage(t *testing.T) {
+	m := jsonSampleOutMsg()
+	generated, err := m.MarshalJSON()
+	if err != nil {
+		t.Fatalf("generated marshal: %v", err)
+	}
+	reflected, err := json.Marshal((*jsonReflectOutMsg)(m))
+	if err != nil {
+		t.Fatalf("reflect marshal: %v", err)
+	}
+	if !bytes.Equal(generated, reflected) {
+		t.Fatalf("generated JSON differs from encoding/json\ngenerated: %s\nreflect:   %s", generated, reflected)
+	}
+
+	data := []byte(`{"type":"req", "procedure":"ango", "cb_id":42, "data":{"a": [1, {"b": "c"}]}, "error":"ango", "meta":{"a":"b"}, "ango":true}`)
+	decoded := &angoInMsg{}
+	err = decoded.UnmarshalJSON(data)
+	if err != nil {
+		t.Fatalf("generated unmarshal: %v", err)
+	}
+	expected := &jsonReflectInMsg{}
+	err = json.Unmarshal(data, expected)
+	if err != nil {
+		t.Fatalf("reflect unmarshal: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, (*angoInMsg)(expected)) {
+		t.Fatalf("generated unmarshal differs from encoding/json\ndecoded:  %#v\nexpected: %#v", decoded, expected)
+	}
+}
+
+func BenchmarkJSONMarshalMessage(b *testing.B) {
+	m := jsonSampleOutMsg()
+	b.Run("generated", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := m.MarshalJSON(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("reflect", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := json.Marshal((*jsonReflectOutMsg)(m)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestJSONMaxDepth(t *testing.T) {
+	nested := func(key string, depth int) []byte {
+		return []byte(`{"type":"req","` + key + `":` + strings.Repeat("[", depth) + strings.Repeat("]", depth) + `}`)
+	}
+	// data is skipped into a json.RawMessage, unknown members are skipped
+	for _, key := range []string{"data", "unknown"} {
+		err := (&angoInMsg{}).UnmarshalJSON(nested(key, 100))
+		if err != nil {
+			t.Fatalf("%s nested within the limit: %v", key, err)
+		}
+		err = (&angoInMsg{}).UnmarshalJSON(nested(key, jsonMaxDepth+1))
+		if err == nil || !strings.Contains(err.Error(), "exceeded max depth") {
+			t.Fatalf("%s nested beyond the limit: got error %v, expected exceeded max depth", key, err)
+		}
+	}
+}
+
+func BenchmarkJSONUnmarshalMessage(b *testing.B) {
+	data := []byte(`{"type":"req","procedure":"ango","cb_id":42,"data":{"a":42,"b":42},"meta":{"a":"ango"}}`)
+	b.Run("generated", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := (&angoInMsg{}).UnmarshalJSON(data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("reflect", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := json.Unmarshal(data, &jsonReflectInMsg{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+{{range .Service.Types}}{{if .GoJSONMethods}}
+	{{template "jsonTest" .GoJSONTest}}
+{{end}}{{end}}
+
+{{range .Service.ServerProcedures}}
+	{{if .Args}}{{template "jsonTest" (.GoJSONArgsData "angoServerArgsData").Test}}{{end}}
+	{{if and (not .Oneway) .Rets}}{{template "jsonTest" (.GoJSONRetsData "angoServerRetsData").Test}}{{end}}
+{{end}}
+
+{{range .Service.ClientProcedures}}
+	{{if .Args}}{{template "jsonTest" (.GoJSONArgsData "angoClientArgsData").Test}}{{end}}
+	{{if and (not .Oneway) .Rets}}{{template "jsonTest" (.GoJSONRetsData "angoClientRetsData").Test}}{{end}}
+{{end}}
@@ -0,0 +1,888 @@
+// WARNING This is generated code by the ango tool (github.com/GeertJohan/ango)
+// DO NOT EDIT unless you know what you're doing!
+// The generated code requires Go 1.21 or later.
+
+package {{.PackageName}}
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Reference import that is only used by generated code for definitions with maps.
+var _ = sort.Slice
+
+// The MarshalJSON and UnmarshalJSON methods in this file encode and decode the generated types without reflection.
+// Their output is the same as the output of encoding/json for these types.
+// The JSON codec calls them directly, also for the message envelope, so messages are encoded and decoded without reflection.
+// Generation of this file can be disabled with the --no-fast-json flag.
+
+{{range .Service.Types}}{{if not .GoIsBuiltin}}
+	// jsonEncode{{.CapitalizedName}} appends v as JSON to e
+	func jsonEncode{{.CapitalizedName}}(e *jsonEncoder, v *{{.CapitalizedName}}) {
+		{{.GoJSONEncode -}}
+	}
+
+	// jsonDecode{{.CapitalizedName}} decodes the next JSON value from d into v
+	func jsonDecode{{.CapitalizedName}}(d *jsonDecoder, v *{{.CapitalizedName}}) {
+		{{.GoJSONDecode -}}
+	}
+
+	{{if .GoJSONMethods}}
+		// MarshalJSON implements json.Marshaler
+		func (v {{.CapitalizedName}}) MarshalJSON() ([]byte, error) {
+			return jsonMarshal(func(e *jsonEncoder) {
+				jsonEncode{{.CapitalizedName}}(e, &v)
+			})
+		}
+
+		// UnmarshalJSON implements json.Unmarshaler
+		func (v *{{.CapitalizedName}}) UnmarshalJSON(data []byte) error {
+			d := &jsonDecoder{data: data}
+			jsonDecode{{.CapitalizedName}}(d, v)
+			return d.end()
+		}
+	{{end}}
+{{end}}{{end}}
+
+{{define "paramsMethods"}}
+	// MarshalJSON implements json.Marshaler
+	func (v *{{.Name}}) MarshalJSON() ([]byte, error) {
+		return jsonMarshal(v.encodeJSON)
+	}
+
+	// encodeJSON appends v as JSON to e, it implements jsonEncodable
+	func (v *{{.Name}}) encodeJSON(e *jsonEncoder) {
+		if v == nil {
+			e.null()
+			return
+		}
+		{{.Params.GoJSONEncode -}}
+	}
+
+	// UnmarshalJSON implements json.Unmarshaler
+	func (v *{{.Name}}) UnmarshalJSON(data []byte) error {
+		d := &jsonDecoder{data: data}
+		{{.Params.GoJSONDecode -}}
+		return d.end()
+	}
+{{end}}
+
+{{range .Service.ServerProcedures}}
+	{{template "paramsMethods" .GoJSONArgsData "angoServerArgsData"}}
+	{{if not .Oneway}}
+		{{template "paramsMethods" .GoJSONRetsData "angoServerRetsData"}}
+	{{end}}
+{{end}}
+
+{{range .Service.ClientProcedures}}
+	{{template "paramsMethods" .GoJSONArgsData "angoClientArgsData"}}
+	{{if not .Oneway}}
+		{{template "paramsMethods" .GoJSONRetsData "angoClientRetsData"}}
+	{{end}}
+{{end}}
+
+// MarshalJSON implements json.Marshaler for the message envelope.
+func (m angoOutMsg) MarshalJSON() ([]byte, error) {
+	return jsonMarshal(m.encodeJSON)
+}
+
+// encodeJSON appends the message as JSON to e, fields are omitted like the omitempty tags on angoOutMsg define.
+func (m *angoOutMsg) encodeJSON(e *jsonEncoder) {
+	if m == nil {
+		e.null()
+		return
+	}
+	e.raw(`{"type":`)
+	e.string(m.Type)
+	if m.Procedure != "" {
+		e.raw(`,"procedure":`)
+		e.string(m.Procedure)
+	}
+	if m.CallbackID != 0 {
+		e.raw(`,"cb_id":`)
+		e.uint(m.CallbackID)
+	}
+	if m.Data != nil {
+		e.raw(`,"data":`)
+		jsonEncodeData(e, m.Data)
+	}
+	if m.Error != nil {
+		e.raw(`,"error":{"type":`)
+		e.string(m.Error.Type)
+		e.raw(`,"message":`)
+		e.string(m.Error.Message)
+		e.byte('}')
+	}
+	if len(m.Meta) > 0 {
+		keys := make([]string, 0, len(m.Meta))
+		for k := range m.Meta {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		e.raw(`,"meta":{`)
+		for i, k := range keys {
+			if i > 0 {
+				e.byte(',')
+			}
+			e.string(k)
+			e.byte(':')
+			e.string(m.Meta[k])
+		}
+		e.byte('}')
+	}
+	e.byte('}')
+}
+
+// jsonEncodeData appends the data of a message to e. The generated params structs and batches are encoded
+// directly, other values by encoding/json.
+func jsonEncodeData(e *jsonEncoder, data interface{}) {
+	switch data := data.(type) {
+	case jsonEncodable:
+		data.encodeJSON(e)
+	case []*angoOutMsg:
+		if data == nil {
+			e.null()
+			return
+		}
+		e.byte('[')
+		for i, m := range data {
+			if i > 0 {
+				e.byte(',')
+			}
+			m.encodeJSON(e)
+		}
+		e.byte(']')
+	default:
+		b, err := json.Marshal(data)
+		if err != nil {
+			e.fail(err)
+			e.null()
+			return
+		}
+		e.buf = append(e.buf, b...)
+	}
+}
+
+// UnmarshalJSON implements json.Unmarshaler for the message envelope, data and error are kept raw.
+// Unlike encoding/json, a null data or error is kept as nil, like the MessagePack codec does.
+func (m *angoInMsg) UnmarshalJSON(data []byte) error {
+	d := &jsonDecoder{data: data}
+	if !d.null() && d.objectStart() {
+		for d.objectNext() {
+			switch jsonField(d.key, "type", "procedure", "cb_id", "data", "error", "meta") {
+			case 0:
+				if !d.null() {
+					m.Type = d.string()
+				}
+			case 1:
+				if !d.null() {
+					m.Procedure = d.string()
+				}
+			case 2:
+				if !d.null() {
+					m.CallbackID = d.uint(64)
+				}
+			case 3:
+				m.Data = d.raw()
+			case 4:
+				m.Error = d.raw()
+			case 5:
+				if d.null() {
+					m.Meta = nil
+				} else if d.objectStart() {
+					if m.Meta == nil {
+						m.Meta = make(map[string]string)
+					}
+					for d.objectNext() {
+						k := string(d.key)
+						var v string
+						if !d.null() {
+							v = d.string()
+						}
+						m.Meta[k] = v
+					}
+				}
+			default:
+				d.skip()
+			}
+		}
+	}
+	return d.end()
+}
+
+// jsonEncodable is implemented by the generated params structs, which can be appended to a jsonEncoder directly.
+type jsonEncodable interface {
+	encodeJSON(e *jsonEncoder)
+}
+
+// jsonEncoder appends JSON to buf. The first error is kept in err.
+type jsonEncoder struct {
+	buf []byte
+	err error
+}
+
+// jsonEncoderMaxBuffer is the largest buffer kept in jsonEncoderPool, encoders that grew beyond it are dropped.
+const jsonEncoderMaxBuffer = 64 * 1024
+
+// jsonEncoderPool holds jsonEncoders with an empty buffer, so encoding a value doesn't grow a new buffer every time.
+var jsonEncoderPool = sync.Pool{
+	New: func() interface{} {
+		return &jsonEncoder{buf: make([]byte, 0, 512)}
+	},
+}
+
+// jsonMarshal runs encode with a pooled jsonEncoder and returns a copy of the output.
+func jsonMarshal(encode func(e *jsonEncoder)) ([]byte, error) {
+	e := jsonEncoderPool.Get().(*jsonEncoder)
+	encode(e)
+	var b []byte
+	err := e.err
+	if err == nil {
+		b = append([]byte(nil), e.buf...)
+	}
+	if cap(e.buf) <= jsonEncoderMaxBuffer {
+		e.buf, e.err = e.buf[:0], nil
+		jsonEncoderPool.Put(e)
+	}
+	return b, err
+}
+
+// fail records err, unless an error was recorded before
+func (e *jsonEncoder) fail(err error) {
+	if e.err == nil {
+		e.err = err
+	}
+}
+
+func (e *jsonEncoder) byte(c byte) {
+	e.buf = append(e.buf, c)
+}
+
+// raw appends s, which must be valid JSON
+func (e *jsonEncoder) raw(s string) {
+	e.buf = append(e.buf, s...)
+}
+
+func (e *jsonEncoder) null() {
+	e.buf = append(e.buf, "null"...)
+}
+
+func (e *jsonEncoder) bool(b bool) {
+	e.buf = strconv.AppendBool(e.buf, b)
+}
+
+func (e *jsonEncoder) int(i int64) {
+	e.buf = strconv.AppendInt(e.buf, i, 10)
+}
+
+func (e *jsonEncoder) uint(u uint64) {
+	e.buf = strconv.AppendUint(e.buf, u, 10)
+}
+
+// float appends f like encoding/json does, NaN and infinity are not valid JSON.
+func (e *jsonEncoder) float(f float64, bits int) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		e.fail(fmt.Errorf("json: unsupported value: %s", strconv.FormatFloat(f, 'g', -1, bits)))
+		e.null()
+		return
+	}
+
+	// use exponent format for very large and very small numbers, like ES6
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	e.buf = strconv.AppendFloat(e.buf, f, format, -1, bits)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		n := len(e.buf)
+		if n >= 4 && e.buf[n-4] == 'e' && e.buf[n-3] == '-' && e.buf[n-2] == '0' {
+			e.buf[n-2] = e.buf[n-1]
+			e.buf = e.buf[:n-1]
+		}
+	}
+}
+
+// string appends s as JSON string. Like encoding/json, HTML characters are escaped and invalid UTF-8 is replaced.
+func (e *jsonEncoder) string(s string) {
+	const hex = "0123456789abcdef"
+	e.buf = append(e.buf, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			e.buf = append(e.buf, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				e.buf = append(e.buf, '\\', c)
+			case '\b':
+				e.buf = append(e.buf, '\\', 'b')
+			case '\f':
+				e.buf = append(e.buf, '\\', 'f')
+			case '\n':
+				e.buf = append(e.buf, '\\', 'n')
+			case '\r':
+				e.buf = append(e.buf, '\\', 'r')
+			case '\t':
+				e.buf = append(e.buf, '\\', 't')
+			default:
+				e.buf = append(e.buf, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			e.buf = append(e.buf, s[start:i]...)
+			e.buf = append(e.buf, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		// U+2028 and U+2029 are valid JSON, but not valid javascript
+		if r == '\u2028' || r == '\u2029' {
+			e.buf = append(e.buf, s[start:i]...)
+			e.buf = append(e.buf, '\\', 'u', '2', '0', '2', hex[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	e.buf = append(e.buf, s[start:]...)
+	e.buf = append(e.buf, '"')
+}
+
+// bytes appends b as base64 encoded JSON string, like encoding/json does for byte slices.
+func (e *jsonEncoder) bytes(b []byte) {
+	e.buf = append(e.buf, '"')
+	n := len(e.buf)
+	e.buf = append(e.buf, make([]byte, base64.StdEncoding.EncodedLen(len(b)))...)
+	base64.StdEncoding.Encode(e.buf[n:], b)
+	e.buf = append(e.buf, '"')
+}
+
+// jsonIntKeyLess reports whether map key a sorts before b. Like encoding/json, keys are sorted as strings.
+func jsonIntKeyLess(a, b int64) bool {
+	var as, bs [20]byte
+	return string(strconv.AppendInt(as[:0], a, 10)) < string(strconv.AppendInt(bs[:0], b, 10))
+}
+
+// jsonUintKeyLess reports whether map key a sorts before b. Like encoding/json, keys are sorted as strings.
+func jsonUintKeyLess(a, b uint64) bool {
+	var as, bs [20]byte
+	return string(strconv.AppendUint(as[:0], a, 10)) < string(strconv.AppendUint(bs[:0], b, 10))
+}
+
+// jsonDecoder reads JSON values from data. The first error is kept in err, after an error no more values are read.
+type jsonDecoder struct {
+	data []byte
+	pos  int
+
+	// key holds the key for the object member, as read by objectNext.
+	// It is only valid until the next value is read.
+	key []byte
+
+	// first is true when no member or element was read since objectStart or arrayStart
+	first bool
+
+	// depth is the nesting of the objects and arrays being skipped
+	depth int
+
+	err error
+}
+
+// jsonMaxDepth is the maximum nesting of skipped objects and arrays, the same limit as encoding/json.
+// Without it, a message of nested arrays would exhaust the stack.
+const jsonMaxDepth = 10000
+
+// fail records err, unless an error was recorded before, and stops reading
+func (d *jsonDecoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+	d.pos = len(d.data)
+}
+
+// failSyntax records a syntax error at the current position
+func (d *jsonDecoder) failSyntax(expected string) {
+	if d.pos >= len(d.data) {
+		d.fail(errors.New("json: unexpected end of JSON input"))
+		return
+	}
+	d.fail(fmt.Errorf("json: invalid character %q at offset %d, expected %s", d.data[d.pos], d.pos, expected))
+}
+
+// end checks that there is no data after the decoded value and returns the error, if any.
+func (d *jsonDecoder) end() error {
+	if d.err == nil && d.peek() != 0 {
+		d.failSyntax("end of JSON input")
+	}
+	return d.err
+}
+
+// peek skips whitespace and returns the next byte, 0 at the end of data
+func (d *jsonDecoder) peek() byte {
+	for d.pos < len(d.data) {
+		switch c := d.data[d.pos]; c {
+		case ' ', '\t', '\n', '\r':
+			d.pos++
+		default:
+			return c
+		}
+	}
+	return 0
+}
+
+// literal reads lit when it is next in data
+func (d *jsonDecoder) literal(lit string) bool {
+	if d.peek() == lit[0] && bytes.HasPrefix(d.data[d.pos:], []byte(lit)) {
+		d.pos += len(lit)
+		return true
+	}
+	return false
+}
+
+// null reads the next value when it is null
+func (d *jsonDecoder) null() bool {
+	return d.literal("null")
+}
+
+// objectStart reads the start of an object, members are read with objectNext.
+func (d *jsonDecoder) objectStart() bool {
+	if d.peek() != '{' {
+		d.failType("object")
+		return false
+	}
+	d.pos++
+	d.first = true
+	return true
+}
+
+// objectNext reads the key for the next object member into d.key, or the end of the object.
+// The caller must read the member value when objectNext returns true.
+func (d *jsonDecoder) objectNext() bool {
+	c := d.peek()
+	if c == '}' {
+		d.pos++
+		d.first = false
+		return false
+	}
+	if !d.first {
+		if c != ',' {
+			d.failSyntax("',' or '}'")
+			return false
+		}
+		d.pos++
+		c = d.peek()
+	}
+	d.first = false
+	if c != '"' {
+		d.failSyntax("object key")
+		return false
+	}
+	d.key = d.stringBytes()
+	if d.peek() != ':' {
+		d.failSyntax("':'")
+		return false
+	}
+	d.pos++
+	return d.err == nil
+}
+
+// arrayStart reads the start of an array, elements are read with arrayNext.
+func (d *jsonDecoder) arrayStart() bool {
+	if d.peek() != '[' {
+		d.failType("array")
+		return false
+	}
+	d.pos++
+	d.first = true
+	return true
+}
+
+// arrayNext returns true when there is a next element, the caller must read it.
+func (d *jsonDecoder) arrayNext() bool {
+	c := d.peek()
+	if c == ']' {
+		d.pos++
+		d.first = false
+		return false
+	}
+	if !d.first {
+		if c != ',' {
+			d.failSyntax("',' or ']'")
+			return false
+		}
+		d.pos++
+	}
+	d.first = false
+	return d.err == nil
+}
+
+// failType records an error for a value that can't be decoded into the expected Go value
+func (d *jsonDecoder) failType(expected string) {
+	var value string
+	switch d.peek() {
+	case 0:
+		d.failSyntax(expected)
+		return
+	case '{':
+		value = "object"
+	case '[':
+		value = "array"
+	case '"':
+		value = "string"
+	case 't', 'f':
+		value = "bool"
+	case 'n':
+		value = "null"
+	default:
+		value = "number"
+	}
+	d.fail(fmt.Errorf("json: cannot unmarshal %s into Go value of type %s at offset %d", value, expected, d.pos))
+}
+
+// stringBytes reads a string. The returned slice may point into data when the string has no escapes.
+func (d *jsonDecoder) stringBytes() []byte {
+	if d.peek() != '"' {
+		d.failType("string")
+		return nil
+	}
+	d.pos++
+	start := d.pos
+	for d.pos < len(d.data) {
+		c := d.data[d.pos]
+		switch {
+		case c == '"':
+			d.pos++
+			return d.data[start : d.pos-1]
+		case c == '\\' || c < 0x20 || c >= utf8.RuneSelf:
+			return d.stringSlow(start)
+		}
+		d.pos++
+	}
+	d.failSyntax("'\"'")
+	return nil
+}
+
+// stringSlow reads the remainder of a string that has escapes or non-ASCII characters.
+func (d *jsonDecoder) stringSlow(start int) []byte {
+	b := make([]byte, 0, d.pos-start+16)
+	b = append(b, d.data[start:d.pos]...)
+	for d.pos < len(d.data) {
+		c := d.data[d.pos]
+		switch {
+		case c == '"':
+			d.pos++
+			return b
+		case c < 0x20:
+			d.failSyntax("string character")
+			return nil
+		case c == '\\':
+			if d.pos+1 >= len(d.data) {
+				d.pos++
+				d.failSyntax("'\"'")
+				return nil
+			}
+			d.pos++
+			switch e := d.data[d.pos]; e {
+			case '"', '\\', '/':
+				b = append(b, e)
+			case 'b':
+				b = append(b, '\b')
+			case 'f':
+				b = append(b, '\f')
+			case 'n':
+				b = append(b, '\n')
+			case 'r':
+				b = append(b, '\r')
+			case 't':
+				b = append(b, '\t')
+			case 'u':
+				r := d.hex4(d.pos + 1)
+				if r < 0 {
+					d.failSyntax("hex digits")
+					return nil
+				}
+				d.pos += 4
+				if utf16.IsSurrogate(r) {
+					r2 := rune(-1)
+					if d.pos+2 < len(d.data) && d.data[d.pos+1] == '\\' && d.data[d.pos+2] == 'u' {
+						r2 = d.hex4(d.pos + 3)
+					}
+					if r = utf16.DecodeRune(r, r2); r != utf8.RuneError {
+						d.pos += 6
+					}
+				}
+				b = utf8.AppendRune(b, r)
+			default:
+				d.failSyntax("escape character")
+				return nil
+			}
+			d.pos++
+		case c < utf8.RuneSelf:
+			b = append(b, c)
+			d.pos++
+		default:
+			// like encoding/json, invalid UTF-8 is replaced by U+FFFD
+			r, size := utf8.DecodeRune(d.data[d.pos:])
+			d.pos += size
+			b = utf8.AppendRune(b, r)
+		}
+	}
+	d.failSyntax("'\"'")
+	return nil
+}
+
+// hex4 returns the value of four hex digits at pos, or -1
+func (d *jsonDecoder) hex4(pos int) rune {
+	if pos+4 > len(d.data) {
+		return -1
+	}
+	var r rune
+	for _, c := range d.data[pos : pos+4] {
+		switch {
+		case '0' <= c && c <= '9':
+			c = c - '0'
+		case 'a' <= c && c <= 'f':
+			c = c - 'a' + 10
+		case 'A' <= c && c <= 'F':
+			c = c - 'A' + 10
+		default:
+			return -1
+		}
+		r = r*16 + rune(c)
+	}
+	return r
+}
+
+func (d *jsonDecoder) string() string {
+	return string(d.stringBytes())
+}
+
+// bytes reads a base64 encoded string, like encoding/json does for byte slices.
+func (d *jsonDecoder) bytes() []byte {
+	s := d.stringBytes()
+	if s == nil {
+		return nil
+	}
+	b := make([]byte, base64.StdEncoding.DecodedLen(len(s)))
+	n, err := base64.StdEncoding.Decode(b, s)
+	if err != nil {
+		d.fail(err)
+		return nil
+	}
+	return b[:n]
+}
+
+func (d *jsonDecoder) bool() bool {
+	switch {
+	case d.literal("true"):
+		return true
+	case d.literal("false"):
+		return false
+	}
+	d.failType("bool")
+	return false
+}
+
+// number reads a number literal, the returned slice points into data
+func (d *jsonDecoder) number(expected string) []byte {
+	c := d.peek()
+	if c != '-' && (c < '0' || c > '9') {
+		d.failType(expected)
+		return nil
+	}
+	start := d.pos
+	if c == '-' {
+		d.pos++
+	}
+	// no leading zeros
+	if d.pos < len(d.data) && d.data[d.pos] == '0' {
+		d.pos++
+	} else if !d.digits() {
+		d.failSyntax("digit")
+		return nil
+	}
+	if d.pos < len(d.data) && d.data[d.pos] == '.' {
+		d.pos++
+		if !d.digits() {
+			d.failSyntax("digit")
+			return nil
+		}
+	}
+	if d.pos < len(d.data) && (d.data[d.pos] == 'e' || d.data[d.pos] == 'E') {
+		d.pos++
+		if d.pos < len(d.data) && (d.data[d.pos] == '+' || d.data[d.pos] == '-') {
+			d.pos++
+		}
+		if !d.digits() {
+			d.failSyntax("digit")
+			return nil
+		}
+	}
+	return d.data[start:d.pos]
+}
+
+// digits reads one or more digits, it returns false when there is no digit.
+func (d *jsonDecoder) digits() bool {
+	start := d.pos
+	for d.pos < len(d.data) && '0' <= d.data[d.pos] && d.data[d.pos] <= '9' {
+		d.pos++
+	}
+	return d.pos > start
+}
+
+// int reads an integer of size bits, 0 for int
+func (d *jsonDecoder) int(bits int) int64 {
+	s := d.number("int")
+	if d.err != nil {
+		return 0
+	}
+	i, err := strconv.ParseInt(string(s), 10, bits)
+	if err != nil {
+		d.fail(fmt.Errorf("json: cannot unmarshal number %s into Go value of type int%s", s, jsonBitsName(bits)))
+	}
+	return i
+}
+
+// uint reads an unsigned integer of size bits, 0 for uint
+func (d *jsonDecoder) uint(bits int) uint64 {
+	s := d.number("uint")
+	if d.err != nil {
+		return 0
+	}
+	u, err := strconv.ParseUint(string(s), 10, bits)
+	if err != nil {
+		d.fail(fmt.Errorf("json: cannot unmarshal number %s into Go value of type uint%s", s, jsonBitsName(bits)))
+	}
+	return u
+}
+
+// float reads a floating point number of size bits
+func (d *jsonDecoder) float(bits int) float64 {
+	s := d.number("float")
+	if d.err != nil {
+		return 0
+	}
+	f, err := strconv.ParseFloat(string(s), bits)
+	if err != nil {
+		d.fail(fmt.Errorf("json: cannot unmarshal number %s into Go value of type float%d", s, bits))
+	}
+	return f
+}
+
+// keyInt parses the object key as integer of size bits
+func (d *jsonDecoder) keyInt(bits int) int64 {
+	i, err := strconv.ParseInt(string(d.key), 10, bits)
+	if err != nil {
+		d.fail(fmt.Errorf("json: cannot unmarshal number %s into Go value of type int%s", d.key, jsonBitsName(bits)))
+	}
+	return i
+}
+
+// keyUint parses the object key as unsigned integer of size bits
+func (d *jsonDecoder) keyUint(bits int) uint64 {
+	u, err := strconv.ParseUint(string(d.key), 10, bits)
+	if err != nil {
+		d.fail(fmt.Errorf("json: cannot unmarshal number %s into Go value of type uint%s", d.key, jsonBitsName(bits)))
+	}
+	return u
+}
+
+// raw reads the next value and returns it undecoded, the returned slice points into data. null is returned as nil.
+func (d *jsonDecoder) raw() []byte {
+	if d.null() {
+		return nil
+	}
+	start := d.pos
+	d.skip()
+	if d.err != nil {
+		return nil
+	}
+	return d.data[start:d.pos]
+}
+
+// nest increments the depth before skipping an object or array, it fails when the depth exceeds jsonMaxDepth.
+func (d *jsonDecoder) nest() bool {
+	d.depth++
+	if d.depth > jsonMaxDepth {
+		d.fail(fmt.Errorf("json: exceeded max depth at offset %d", d.pos))
+		return false
+	}
+	return true
+}
+
+// skip reads and discards the next value
+func (d *jsonDecoder) skip() {
+	switch d.peek() {
+	case '{':
+		if !d.nest() {
+			return
+		}
+		d.objectStart()
+		for d.objectNext() {
+			d.skip()
+		}
+		d.depth--
+	case '[':
+		if !d.nest() {
+			return
+		}
+		d.arrayStart()
+		for d.arrayNext() {
+			d.skip()
+		}
+		d.depth--
+	case '"':
+		d.stringBytes()
+	case 't', 'f':
+		d.bool()
+	case 'n':
+		if !d.null() {
+			d.failSyntax("value")
+		}
+	default:
+		d.number("value")
+	}
+}
+
+func jsonBitsName(bits int) string {
+	if bits == 0 {
+		return ""
+	}
+	return strconv.Itoa(bits)
+}
+
+// jsonField returns the index of the name matching key, or -1. Like encoding/json, an exact match
+// is preferred over a case-insensitive match.
+func jsonField(key []byte, names ...string) int {
+	for i, name := range names {
+		if string(key) == name {
+			return i
+		}
+	}
+	for i, name := range names {
+		if bytes.EqualFold(key, []byte(name)) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,11 @@
+// WARNING This is generated code by the ango tool (github.com/GeertJohan/ango)
+// DO NOT EDIT unless you know what you're doing!
+
+// ES module client for the {{.Service.Name}} service, it doesn't depend on a framework.
+//
+//	import { {{.Service.CapitalizedName}}Client } from "./{{.Service.Name}}.gen.js";
+//	var client = new {{.Service.CapitalizedName}}Client({url: "ws://"+location.host+"/websocket-ango-{{.Service.Name}}"});
+
+{{template "ango-core.tmpl.js" .}}
+
+export { {{.Service.CapitalizedName}}Client, AngoException };
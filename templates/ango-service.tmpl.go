@@ -1,13 +1,22 @@
 // WARNING This is generated code by the ango tool (github.com/GeertJohan/ango)
 // DO NOT EDIT unless you know what you're doing!
+// The generated code requires Go 1.21 or later.
 
 package {{.PackageName}}
 
 import (
+	"context"
 	"errors"
-	"fmt"
 	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
 	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/GeertJohan/go.wstext"
 	"github.com/GeertJohan/go.incremental"
@@ -16,6 +25,14 @@ import (
 
 const ProtocolVersion = "{{.ProtocolVersion}}"
 
+// angoSignatures holds the signature of every procedure, it is advertised during the handshake.
+// Procedures with the same signature on both sides can be called when the protocol versions differ.
+const angoSignatures = "{{.SignatureSet}}"
+
+// angoHandshakeLimit is the read limit for the handshake, Server.MaxMessageSize only applies after it.
+// The handshake holds the signature set of the client, which grows with the number of procedures.
+const angoHandshakeLimit = int64(64*1024 + len(angoSignatures))
+
 var (
 	// ErrIncompatibleVersion indicates a client tried to connect with an incompatible version.
 	ErrIncompatibleVersion = errors.New("incompatible version")
@@ -27,6 +44,9 @@ var (
 	// ErrUnknownProcedure indicates a call request was received for a procedure that was not defined on the server.
 	//++ TODO: simplify to ErrProtocolFault
 	ErrUnknownProcedure     = errors.New("unknown procedure")
+
+	// ErrProcedureUnavailable indicates a procedure can't be called because its signature differs between server and client.
+	ErrProcedureUnavailable = errors.New("procedure unavailable")
 	
 	// ErrInvalidCallbackID indicates a message was received with an unknown callback ID.
 	//++ TODO: simplify to ErrProtocolFault
@@ -34,11 +54,55 @@ var (
 
 	// ErrNotImplementedYet is used during development.
 	ErrNotImplementedYet    = errors.New("not implemented yet")
+
+	// ErrMessageTooLarge indicates a message from the client exceeded Server.MaxMessageSize.
+	ErrMessageTooLarge      = errors.New("message too large")
+
+	// ErrRateLimited indicates a client exceeded Server.RequestRate.
+	ErrRateLimited          = errors.New("request rate limit exceeded")
+
+	// ErrTooManyPendingCalls indicates a call to a client procedure would exceed Server.MaxPendingClientCalls.
+	ErrTooManyPendingCalls  = errors.New("too many pending client calls")
+
+	// ErrServerShutdown is given to Session.Stop when the session was closed by Server.Shutdown.
+	ErrServerShutdown       = errors.New("server shutdown")
+
+	// ErrStreamCancelled indicates a stream was cancelled by the receiving side.
+	ErrStreamCancelled      = errors.New("stream cancelled")
+
+	// ErrStreamOverflow indicates the client sent more stream items than allowed by flow control.
+	//++ TODO: simplify to ErrProtocolFault
+	ErrStreamOverflow       = errors.New("stream overflow")
+
+	// ErrConnectionClosed indicates the connection closed before a stream ended or a client procedure returned.
+	ErrConnectionClosed     = errors.New("connection closed")
+
+	// ErrUnsubscribed indicates a subscription has ended, because the client unsubscribed or the subscription was closed.
+	ErrUnsubscribed         = errors.New("unsubscribed")
+)
+
+// LimitAction defines how a server handles a connection exceeding one of its limits.
+type LimitAction int
+
+const (
+	// LimitActionError rejects the offending call with an error.
+	// Requests exceeding the rate are answered with an error response (oneway requests are dropped),
+	// calls exceeding the pending client calls return ErrTooManyPendingCalls.
+	LimitActionError = LimitAction(iota)
+
+	// LimitActionDisconnect closes the connection.
+	LimitActionDisconnect
 )
 
 const (
 	msgTypeRequest  = "req"
 	msgTypeResponse = "res"
+	msgTypeGoingAway = "goingAway"
+	msgTypeStreamItem   = "item"
+	msgTypeStreamEnd    = "end"
+	msgTypeStreamCancel = "cancel"
+	msgTypeStreamCredit = "credit"
+	msgTypeBatch        = "batch"
 )
 
 // root structure for incoming message json
@@ -48,6 +112,7 @@ type angoInMsg struct {
 	CallbackID uint64          `json:"cb_id"`     // callback ID for request or response
 	Data       json.RawMessage `json:"data"`      // remain raw, depends on procedure
 	Error      json.RawMessage `json:"error"`     // remain raw, depens on ??
+	Meta       map[string]string `json:"meta"`    // optional, metadata for the call
 }
 
 // root structure for outgoing message json
@@ -57,6 +122,7 @@ type angoOutMsg struct {
 	CallbackID uint64        `json:"cb_id,omitempty"`     // callback ID for request or response
 	Data       interface{}   `json:"data,omitempty"`      // remain raw, depends on procedure
 	Error      *angoOutError `json:"error,omitempty"`     // when not-nil, an error ocurred
+	Meta       map[string]string `json:"meta,omitempty"` // optional, metadata for the call
 }
 
 type angoOutError struct {
@@ -101,11 +167,73 @@ type Session interface {
 	Stop(err error)
 
 	{{range .Service.ServerProcedures}}
-		// {{.CapitalizedName}} is a ango procedure defined in the .ango file
-		{{.CapitalizedName}}( {{.GoArgs}} )( {{.GoRets}} )
+		{{if .Stream}}
+			// {{.CapitalizedName}} is a ango stream procedure defined in the .ango file.
+			// Items are sent using the stream. The stream ends when {{.CapitalizedName}} returns.
+			{{.CapitalizedName}}( {{.GoStreamArgs}} )( err error )
+		{{else if .Subscribe}}
+			// {{.CapitalizedName}} is a ango subscribe procedure defined in the .ango file.
+			// It is called when the client subscribes, returning an error rejects the subscription.
+			// Values are published using the subscription, which stays active after {{.CapitalizedName}} has returned.
+			{{.CapitalizedName}}( {{.GoSubscribeArgs}} )( err error )
+		{{else}}
+			// {{.CapitalizedName}} is a ango procedure defined in the .ango file
+			{{.CapitalizedName}}( {{.GoArgs}} )( {{.GoRets}} )
+		{{end}}
 	{{end}}
 }
 
+{{range .Service.ServerProcedures}}{{if .Stream}}
+	// {{.CapitalizedName}}Stream sends the items for a call to the stream procedure {{.Name}}.
+	type {{.CapitalizedName}}Stream struct {
+		ctx    context.Context
+		stream *angoStream
+	}
+
+	// Context returns the context for the stream. It is cancelled when the client cancels the stream or the connection closes.
+	func (s *{{.CapitalizedName}}Stream) Context() context.Context {
+		return s.ctx
+	}
+
+	// Send sends an item to the client. Send blocks while the client has not consumed enough of the earlier items (flow control).
+	// Send returns ErrStreamCancelled when the stream was cancelled.
+	func (s *{{.CapitalizedName}}Stream) Send( {{.Rets.GoParameterList}} ) error {
+		return s.stream.send(s.ctx, &angoServerRetsData{{.CapitalizedName}}{
+			{{range .Rets}}
+				{{.CapitalizedName}}: {{.Name}},{{end}}
+		})
+	}
+{{else if .Subscribe}}
+	// {{.CapitalizedName}}Subscription publishes values for a subscription to the subscribe procedure {{.Name}}.
+	// A subscription is active until the client unsubscribes, Close is called or the connection closes.
+	type {{.CapitalizedName}}Subscription struct {
+		ctx context.Context
+		sub *angoSubscription
+	}
+
+	// Context returns the context for the subscription. It is cancelled when the subscription ends.
+	// Use it to stop publishing and to remove the subscription from any bookkeeping.
+	func (s *{{.CapitalizedName}}Subscription) Context() context.Context {
+		return s.ctx
+	}
+
+	// Publish sends a new value to the client. Publish does not block, when the client has not consumed
+	// enough of the earlier values (flow control), only the latest value is sent once the client catches up.
+	// Publish returns ErrUnsubscribed when the subscription has ended.
+	func (s *{{.CapitalizedName}}Subscription) Publish( {{.Rets.GoParameterList}} ) error {
+		return s.sub.publish(&angoServerRetsData{{.CapitalizedName}}{
+			{{range .Rets}}
+				{{.CapitalizedName}}: {{.Name}},{{end}}
+		})
+	}
+
+	// Close ends the subscription. When err is not nil, it is sent to the client.
+	// A published value that is still waiting for credit is sent before the subscription ends.
+	func (s *{{.CapitalizedName}}Subscription) Close(err error) {
+		s.sub.close(err)
+	}
+{{end}}{{end}}
+
 // // NewSessionFunc must return a new instance implementing {{.Service.CapitalizedName}}SessionHandler
 // //++ TODO: rename to NewSessionFunc (?) when generated code gets it's own package
 // type NewSessionFunc func(*Client)(handler SessionHandler)
@@ -117,6 +245,320 @@ type Session interface {
 // func NewServer(NewSessionFunc, *Config) *Server
 // type Server struct{ /*unexported fields*/ }
 
+// LogLevel indicates the severity of a log message.
+type LogLevel int
+
+// LogLevel's
+const (
+	LogDebug = LogLevel(iota)
+	LogInfo
+	LogError
+)
+
+// Logger is used by the server to log what is going on.
+// The Logger must be safe for concurrent use.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// nopLogger is the default Logger, it discards all messages.
+type nopLogger struct{}
+
+func (nopLogger) Debugf(format string, args ...interface{}) {}
+func (nopLogger) Infof(format string, args ...interface{})  {}
+func (nopLogger) Errorf(format string, args ...interface{}) {}
+
+// stdLogger adapts a *log.Logger to the Logger interface.
+type stdLogger struct {
+	l     *log.Logger
+	level LogLevel
+}
+
+// NewStdLogger returns a Logger writing messages with at least the given level to l.
+// When l is nil, the standard logger from package log is used.
+func NewStdLogger(l *log.Logger, level LogLevel) Logger {
+	if l == nil {
+		l = log.Default()
+	}
+	return &stdLogger{l: l, level: level}
+}
+
+func (sl *stdLogger) logf(level LogLevel, prefix string, format string, args ...interface{}) {
+	if level < sl.level {
+		return
+	}
+	sl.l.Printf(prefix+format, args...)
+}
+
+func (sl *stdLogger) Debugf(format string, args ...interface{}) { sl.logf(LogDebug, "DEBUG ", format, args...) }
+func (sl *stdLogger) Infof(format string, args ...interface{})  { sl.logf(LogInfo, "INFO ", format, args...) }
+func (sl *stdLogger) Errorf(format string, args ...interface{}) { sl.logf(LogError, "ERROR ", format, args...) }
+
+// slogLogger adapts a *slog.Logger to the Logger interface.
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// NewSlogLogger returns a Logger writing to l. Levels map to slog.LevelDebug, slog.LevelInfo and slog.LevelError,
+// filtering is left to the slog.Handler. When l is nil, slog.Default() is used.
+func NewSlogLogger(l *slog.Logger) Logger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return &slogLogger{l: l}
+}
+
+func (sl *slogLogger) Debugf(format string, args ...interface{}) { sl.l.Debug(fmt.Sprintf(format, args...)) }
+func (sl *slogLogger) Infof(format string, args ...interface{})  { sl.l.Info(fmt.Sprintf(format, args...)) }
+func (sl *slogLogger) Errorf(format string, args ...interface{}) { sl.l.Error(fmt.Sprintf(format, args...)) }
+
+// MetricsCollector receives measurements from the server. It must be safe for concurrent use.
+// Metrics implements MetricsCollector and serves the measurements over http.
+type MetricsCollector interface {
+	// ConnectionError is called when setting up an incomming connection failed.
+	ConnectionError()
+
+	// SessionStarted and SessionStopped are called when a session starts and stops.
+	SessionStarted()
+	SessionStopped()
+
+	// Call is called when a procedure call finished. side is "server" for server procedures and "client" for client procedures.
+	// For oneway client procedures, duration is the time it took to send the call.
+	Call(side string, procedure string, duration time.Duration, err error)
+
+	// PendingClientCalls is called when the number of client calls waiting for a response changes.
+	PendingClientCalls(delta int)
+}
+
+// metricsDurationBuckets are the upper bounds (in seconds) for the call duration histogram.
+var metricsDurationBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10}
+
+// metricsCall holds the measurements for a single procedure.
+type metricsCall struct {
+	count    uint64
+	errors   uint64
+	sum      float64
+	buckets  []uint64 // counts per bucket, not cumulative
+}
+
+// Metrics is a MetricsCollector that keeps measurements in memory.
+// Metrics implements http.Handler, it serves all measurements in the Prometheus text exposition format.
+type Metrics struct {
+	lock               sync.Mutex
+	connectionErrors   uint64
+	sessionsActive     int64
+	sessionsTotal      uint64
+	pendingClientCalls int64
+	calls              map[[2]string]*metricsCall // by side and procedure
+}
+
+// NewMetrics creates a new Metrics instance.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		calls: make(map[[2]string]*metricsCall),
+	}
+}
+
+// ConnectionError implements MetricsCollector.
+func (m *Metrics) ConnectionError() {
+	m.lock.Lock()
+	m.connectionErrors++
+	m.lock.Unlock()
+}
+
+// SessionStarted implements MetricsCollector.
+func (m *Metrics) SessionStarted() {
+	m.lock.Lock()
+	m.sessionsActive++
+	m.sessionsTotal++
+	m.lock.Unlock()
+}
+
+// SessionStopped implements MetricsCollector.
+func (m *Metrics) SessionStopped() {
+	m.lock.Lock()
+	m.sessionsActive--
+	m.lock.Unlock()
+}
+
+// Call implements MetricsCollector.
+func (m *Metrics) Call(side string, procedure string, duration time.Duration, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	key := [2]string{side, procedure}
+	mc := m.calls[key]
+	if mc == nil {
+		mc = &metricsCall{buckets: make([]uint64, len(metricsDurationBuckets))}
+		m.calls[key] = mc
+	}
+	mc.count++
+	if err != nil {
+		mc.errors++
+	}
+	seconds := duration.Seconds()
+	mc.sum += seconds
+	for i, bound := range metricsDurationBuckets {
+		if seconds <= bound {
+			mc.buckets[i]++
+			break
+		}
+	}
+}
+
+// PendingClientCalls implements MetricsCollector.
+func (m *Metrics) PendingClientCalls(delta int) {
+	m.lock.Lock()
+	m.pendingClientCalls += int64(delta)
+	m.lock.Unlock()
+}
+
+// ServeHTTP writes all measurements in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	service := `service="{{.Service.Name}}"`
+
+	fmt.Fprintf(w, "# HELP ango_connection_errors_total Number of incomming connections that failed to set up.\n")
+	fmt.Fprintf(w, "# TYPE ango_connection_errors_total counter\n")
+	fmt.Fprintf(w, "ango_connection_errors_total{%s} %d\n", service, m.connectionErrors)
+	fmt.Fprintf(w, "# HELP ango_sessions_active Number of active sessions.\n")
+	fmt.Fprintf(w, "# TYPE ango_sessions_active gauge\n")
+	fmt.Fprintf(w, "ango_sessions_active{%s} %d\n", service, m.sessionsActive)
+	fmt.Fprintf(w, "# HELP ango_sessions_total Number of sessions started.\n")
+	fmt.Fprintf(w, "# TYPE ango_sessions_total counter\n")
+	fmt.Fprintf(w, "ango_sessions_total{%s} %d\n", service, m.sessionsTotal)
+	fmt.Fprintf(w, "# HELP ango_pending_client_calls Number of client procedure calls waiting for a response.\n")
+	fmt.Fprintf(w, "# TYPE ango_pending_client_calls gauge\n")
+	fmt.Fprintf(w, "ango_pending_client_calls{%s} %d\n", service, m.pendingClientCalls)
+
+	// sort keys for stable output
+	keys := make([][2]string, 0, len(m.calls))
+	for key := range m.calls {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+
+	fmt.Fprintf(w, "# HELP ango_calls_total Number of procedure calls.\n")
+	fmt.Fprintf(w, "# TYPE ango_calls_total counter\n")
+	for _, key := range keys {
+		fmt.Fprintf(w, "ango_calls_total{%s,side=%q,procedure=%q} %d\n", service, key[0], key[1], m.calls[key].count)
+	}
+	fmt.Fprintf(w, "# HELP ango_call_errors_total Number of procedure calls that returned an error.\n")
+	fmt.Fprintf(w, "# TYPE ango_call_errors_total counter\n")
+	for _, key := range keys {
+		fmt.Fprintf(w, "ango_call_errors_total{%s,side=%q,procedure=%q} %d\n", service, key[0], key[1], m.calls[key].errors)
+	}
+	fmt.Fprintf(w, "# HELP ango_call_duration_seconds Duration of procedure calls.\n")
+	fmt.Fprintf(w, "# TYPE ango_call_duration_seconds histogram\n")
+	for _, key := range keys {
+		mc := m.calls[key]
+		labels := fmt.Sprintf("%s,side=%q,procedure=%q", service, key[0], key[1])
+		var cumulative uint64
+		for i, bound := range metricsDurationBuckets {
+			cumulative += mc.buckets[i]
+			fmt.Fprintf(w, "ango_call_duration_seconds_bucket{%s,le=\"%g\"} %d\n", labels, bound, cumulative)
+		}
+		fmt.Fprintf(w, "ango_call_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, mc.count)
+		fmt.Fprintf(w, "ango_call_duration_seconds_sum{%s} %g\n", labels, mc.sum)
+		fmt.Fprintf(w, "ango_call_duration_seconds_count{%s} %d\n", labels, mc.count)
+	}
+}
+
+// Keys for trace context headers in the message meta, as defined by the W3C Trace Context and Baggage specifications.
+const (
+	MetaTraceparent = "traceparent"
+	MetaBaggage     = "baggage"
+)
+
+// Tracer creates spans around procedure calls. It must be safe for concurrent use.
+// Trace context is carried in the message meta using the MetaTraceparent and MetaBaggage keys.
+type Tracer interface {
+	// StartServerSpan starts a span for an incomming call to a server procedure.
+	// meta holds the trace context sent by the client, it is nil when the client didn't send any.
+	StartServerSpan(ctx context.Context, procedure string, meta map[string]string) (context.Context, Span)
+
+	// StartClientSpan starts a span for an outgoing call to a client procedure.
+	// The trace context for the new span must be written to meta, it is sent along with the call.
+	StartClientSpan(ctx context.Context, procedure string, meta map[string]string) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	// End ends the span. err is the error returned by the call, or nil.
+	End(err error)
+}
+
+// ConnInfo holds information about the websocket connection for a session.
+type ConnInfo struct {
+	// RemoteAddr is the network address of the client.
+	RemoteAddr string
+
+	// Request is the http request that was upgraded to the websocket connection.
+	Request *http.Request
+
+	// Codec is the name of the codec negotiated for the connection, CodecJSON or CodecMessagePack.
+	Codec string
+
+	// Compression is true when the permessage-deflate extension was negotiated for the connection.
+	Compression bool
+}
+
+// CallInfo describes a single procedure call passing through an interceptor chain.
+type CallInfo struct {
+	// Procedure is the name of the called procedure, as defined in the .ango file.
+	Procedure string
+
+	// Args holds the decoded procedure arguments (angoServerArgsData* or angoClientArgsData*).
+	// An interceptor may replace Args with a value of the same type before calling next.
+	Args interface{}
+
+	// Conn provides information about the connection the call is made on.
+	Conn *ConnInfo
+
+	// Meta holds metadata sent along with the call (e.g. locale, request ID or trace context), it may be nil.
+	// Interceptors for client calls can add values, they are sent to the client.
+	Meta map[string]string
+}
+
+// metaContextKey is the context key for the metadata of an incomming call.
+type metaContextKey struct{}
+
+// MetaFromContext returns the metadata sent along with the incomming call being handled, or nil when there is none.
+// The context given to interceptors for server procedure calls holds the metadata.
+func MetaFromContext(ctx context.Context) map[string]string {
+	meta, _ := ctx.Value(metaContextKey{}).(map[string]string)
+	return meta
+}
+
+// Handler performs a procedure call. The returned value holds the procedure return values
+// (angoServerRetsData* or angoClientRetsData*), it is nil for oneway procedures.
+type Handler func(ctx context.Context, call *CallInfo) (interface{}, error)
+
+// Interceptor wraps a procedure call. It can inspect or modify the call and must call next to continue the chain.
+// Not calling next skips the procedure, the returned values are then used as procedure result.
+type Interceptor func(ctx context.Context, call *CallInfo, next Handler) (interface{}, error)
+
+// chainInterceptors wraps handler with the given interceptors.
+// The first interceptor is the outermost, it is called first.
+func chainInterceptors(interceptors []Interceptor, handler Handler) Handler {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], handler
+		handler = func(ctx context.Context, call *CallInfo) (interface{}, error) {
+			return interceptor(ctx, call, next)
+		}
+	}
+	return handler
+}
+
 // Server handles incomming http requests
 type Server struct {
 	// NewSession is called when a client connects.
@@ -126,145 +568,1288 @@ type Server struct {
 
 	// ErrorIncommingConnection is called when an incomming connection failed to setup properly.
 	ErrorIncommingConnection func(err error)
+
+	// Logger is used to log debug information and errors. When nil, nothing is logged.
+	Logger Logger
+
+	// Metrics receives measurements on sessions and calls. When nil, nothing is measured.
+	Metrics MetricsCollector
+
+	// Tracer creates spans around procedure calls. When nil, no spans are created.
+	Tracer Tracer
+
+	// MaxMessageSize is the maximum size in bytes for a single message received from a client.
+	// A message exceeding this size always disconnects the client. Zero means no limit.
+	// The handshake isn't subject to MaxMessageSize, it has a separate limit.
+	MaxMessageSize int64
+
+	// RequestRate is the number of requests per second a single connection is allowed to make.
+	// Zero means no limit.
+	RequestRate float64
+
+	// RequestBurst is the number of requests a connection can make at once, before RequestRate applies.
+	// When zero, a burst of one request is allowed.
+	RequestBurst int
+
+	// MaxPendingClientCalls is the maximum number of calls to returning client procedures
+	// waiting for a response, per connection. Zero means no limit.
+	MaxPendingClientCalls int
+
+	// LimitAction defines what happens when RequestRate or MaxPendingClientCalls is exceeded.
+	LimitAction LimitAction
+
+	// LimitExceeded is called when a connection exceeds one of the limits set on the server.
+	// err is one of ErrMessageTooLarge, ErrRateLimited or ErrTooManyPendingCalls.
+	LimitExceeded func(info *ConnInfo, err error)
+
+	// JSONOnly disables the binary codecs, all connections use JSON.
+	// Clients that ask for a binary codec fall back to JSON, which is useful while debugging.
+	JSONOnly bool
+
+	// Compression enables negotiation of the permessage-deflate websocket extension (RFC 7692).
+	// When the client supports it, outgoing messages of at least CompressionThreshold bytes are compressed.
+	// Messages from the client are decompressed transparently.
+	Compression bool
+
+	// CompressionThreshold is the minimum size in bytes for an outgoing message to be compressed,
+	// compressing small messages costs more than it saves. When zero, DefaultCompressionThreshold is used.
+	CompressionThreshold int
+
+	interceptors       []Interceptor
+	clientInterceptors []Interceptor
+
+	lock        sync.Mutex
+	shutdown    bool
+	clients     map[*Client]bool
+	connections sync.WaitGroup
+}
+
+// DefaultCompressionThreshold is the compression threshold used when Server.CompressionThreshold is zero.
+const DefaultCompressionThreshold = 1024
+
+// compressionThreshold returns the minimum size for an outgoing message to be compressed.
+func (server *Server) compressionThreshold() int {
+	if server.CompressionThreshold > 0 {
+		return server.CompressionThreshold
+	}
+	return DefaultCompressionThreshold
+}
+
+// negotiateCompression reports whether the upgrader accepts the permessage-deflate extension offered by the client.
+// The websocket package doesn't expose the negotiated extensions, so the offer is checked the same way here.
+func (server *Server) negotiateCompression(r *http.Request) bool {
+	if !server.Compression {
+		return false
+	}
+	for _, header := range r.Header["Sec-Websocket-Extensions"] {
+		for _, ext := range strings.Split(header, ",") {
+			name, _, _ := strings.Cut(ext, ";")
+			if strings.EqualFold(strings.TrimSpace(name), "permessage-deflate") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Use adds interceptors for incomming calls to server procedures.
+// Interceptors are called in the order they were added.
+// Use must be called before the server starts handling connections.
+func (server *Server) Use(interceptors ...Interceptor) {
+	server.interceptors = append(server.interceptors, interceptors...)
+}
+
+// UseClient adds interceptors for outgoing calls to client procedures (made through *Client).
+// Interceptors are called in the order they were added.
+// UseClient must be called before the server starts handling connections.
+func (server *Server) UseClient(interceptors ...Interceptor) {
+	server.clientInterceptors = append(server.clientInterceptors, interceptors...)
+}
+
+// instrument returns interceptors with interceptors for tracing and measuring calls prepended,
+// when server.Tracer or server.Metrics is set. side is either "server" or "client".
+func (server *Server) instrument(side string, interceptors []Interceptor) []Interceptor {
+	var instrumented []Interceptor
+	if tracer := server.Tracer; tracer != nil {
+		instrumented = append(instrumented, func(ctx context.Context, call *CallInfo, next Handler) (interface{}, error) {
+			var span Span
+			if side == "server" {
+				ctx, span = tracer.StartServerSpan(ctx, call.Procedure, call.Meta)
+			} else {
+				if call.Meta == nil {
+					call.Meta = make(map[string]string)
+				}
+				ctx, span = tracer.StartClientSpan(ctx, call.Procedure, call.Meta)
+			}
+			rets, err := next(ctx, call)
+			span.End(err)
+			return rets, err
+		})
+	}
+	if metrics := server.Metrics; metrics != nil {
+		instrumented = append(instrumented, func(ctx context.Context, call *CallInfo, next Handler) (interface{}, error) {
+			start := time.Now()
+			rets, err := next(ctx, call)
+			metrics.Call(side, call.Procedure, time.Since(start), err)
+			return rets, err
+		})
+	}
+	if len(instrumented) == 0 {
+		return interceptors
+	}
+	return append(instrumented, interceptors...)
+}
+
+// pendingClientCalls reports a change in pending client calls to server.Metrics.
+func (server *Server) pendingClientCalls(delta int) {
+	if server.Metrics != nil {
+		server.Metrics.PendingClientCalls(delta)
+	}
+}
+
+// logger returns the Logger for the server, or a no-op Logger when none was set.
+func (server *Server) logger() Logger {
+	if server.Logger != nil {
+		return server.Logger
+	}
+	return nopLogger{}
+}
+
+// incommingConnectionError logs err and reports it to the ErrorIncommingConnection hook.
+func (server *Server) incommingConnectionError(err error) {
+	if server.Metrics != nil {
+		server.Metrics.ConnectionError()
+	}
+	server.logger().Errorf("error setting up incomming connection: %s", err)
+	if server.ErrorIncommingConnection != nil {
+		server.ErrorIncommingConnection(err)
+	}
+}
+
+// limitExceeded reports a limit violation to the LimitExceeded hook.
+func (server *Server) limitExceeded(info *ConnInfo, err error) {
+	server.logger().Infof("client %s exceeded limit: %s", info.RemoteAddr, err)
+	if server.LimitExceeded != nil {
+		server.LimitExceeded(info, err)
+	}
+}
+
+// tokenBucket implements a simple token bucket rate limiter.
+// It is not safe for concurrent use.
+type tokenBucket struct {
+	rate   float64 // tokens added per second
+	burst  float64 // maximum number of tokens
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// take takes a token from the bucket, it returns false when the bucket is empty.
+func (tb *tokenBucket) take() bool {
+	now := time.Now()
+	tb.tokens += now.Sub(tb.last).Seconds() * tb.rate
+	if tb.tokens > tb.burst {
+		tb.tokens = tb.burst
+	}
+	tb.last = now
+	if tb.tokens < 1 {
+		return false
+	}
+	tb.tokens--
+	return true
+}
+
+// Shutdown gracefully stops the server. New connections are refused, and all clients are notified that the server is going away.
+// Shutdown waits for procedure calls that are being handled to finish, and then closes the connections.
+// Session.Stop is called with ErrServerShutdown.
+// When ctx is done before all sessions have stopped, Shutdown returns ctx.Err().
+func (server *Server) Shutdown(ctx context.Context) error {
+	server.lock.Lock()
+	server.shutdown = true
+	clients := make([]*Client, 0, len(server.clients))
+	for client := range server.clients {
+		clients = append(clients, client)
+	}
+	server.lock.Unlock()
+
+	for _, client := range clients {
+		go client.goAway()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		server.connections.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// addClient registers a client so it can be stopped by Shutdown.
+// It returns false when the server is shutting down.
+func (server *Server) addClient(client *Client) bool {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	if server.shutdown {
+		return false
+	}
+	if server.clients == nil {
+		server.clients = make(map[*Client]bool)
+	}
+	server.clients[client] = true
+	return true
+}
+
+func (server *Server) removeClient(client *Client) {
+	server.lock.Lock()
+	delete(server.clients, client)
+	server.lock.Unlock()
 }
 
 // ServeHTTP hijacks incomming http connections and sets up the websocket communication
 func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	conn, err := websocket.Upgrade(w, r, nil, 1024, 1024)
+	server.lock.Lock()
+	if server.shutdown {
+		server.lock.Unlock()
+		http.Error(w, "Server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	server.connections.Add(1)
+	server.lock.Unlock()
+	defer server.connections.Done()
+
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:    1024,
+		WriteBufferSize:   1024,
+		EnableCompression: server.Compression,
+		// errors are handled below, all origins are allowed
+		Error:       func(w http.ResponseWriter, r *http.Request, status int, reason error) {},
+		CheckOrigin: func(r *http.Request) bool { return true },
+	}
+	compression := server.negotiateCompression(r)
+	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		if _, ok := err.(websocket.HandshakeError); ok {
 			http.Error(w, "Not a websocket handshake", 400)
 			return
 		}
-		if server.ErrorIncommingConnection != nil {
-			server.ErrorIncommingConnection(err)
-		}
+		server.incommingConnectionError(err)
 		return
 	}
+	defer conn.Close()
+	conn.SetReadLimit(angoHandshakeLimit)
 
 	// wrap for simple text read/write
 	textconn := wstext.Conn{conn}
 
-	receivedVersion, err := textconn.ReadText()
+	// the version string can be followed by the codecs the client supports, e.g. "<version> msgpack,json"
+	receivedHandshake, err := textconn.ReadText()
 	if err != nil {
-		if server.ErrorIncommingConnection != nil {
-			server.ErrorIncommingConnection(err)
-		}
+		server.incommingConnectionError(err)
 		return
 	}
+	// followed by the signature set of the client, e.g. "<version> msgpack,json <signatures>"
+	receivedVersion, offer, _ := strings.Cut(receivedHandshake, " ")
+	codecOffer, signatureOffer, _ := strings.Cut(offer, " ")
+	var available *angoAvailability
 	if receivedVersion != ProtocolVersion {
-		_ = textconn.WriteText("invalid")
-		fmt.Printf("err: %s\n", err)
-		fmt.Printf("in: '%s'\n", receivedVersion)
-		fmt.Printf("hv: '%s'\n", ProtocolVersion)
-		if server.ErrorIncommingConnection != nil {
-			server.ErrorIncommingConnection(ErrIncompatibleVersion)
+		if signatureOffer != "" {
+			available = negotiateProcedures(signatureOffer)
+		}
+		if available == nil {
+			_ = textconn.WriteText("invalid")
+			server.logger().Debugf("invalid protocol version '%s', expected '%s'", receivedVersion, ProtocolVersion)
+			server.incommingConnectionError(ErrIncompatibleVersion)
+			return
 		}
-		return
 	}
-	err = textconn.WriteText("good")
+	codecName := server.negotiateCodec(codecOffer)
+	switch {
+	case available != nil:
+		// the client learns which server procedures are available from the server's signature set
+		err = textconn.WriteText("good " + codecName + " " + angoSignatures)
+	case codecName == CodecJSON:
+		// plain "good" keeps older clients working
+		err = textconn.WriteText("good")
+	default:
+		err = textconn.WriteText("good " + codecName)
+	}
 	if err != nil {
-		if server.ErrorIncommingConnection != nil {
-			server.ErrorIncommingConnection(err)
-		}
+		server.incommingConnectionError(err)
 		return
 	}
 
-	fmt.Println("Valid protocol version detected")
+	// zero removes the handshake limit
+	conn.SetReadLimit(server.MaxMessageSize)
+
+	if available != nil {
+		server.logger().Infof("protocol version differs, %d procedures unavailable for %s", available.unavailable, conn.RemoteAddr().String())
+	}
+	server.logger().Debugf("valid protocol version detected, using codec %s", codecName)
+
+	// context lives as long as the connection
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
 
 	// create new client instance with conn
 	client := &Client{
 		ws:               conn,
+		codec:            codecs[codecName],
+		ctx:              ctx,
+		done:             make(chan struct{}),
+		info:             &ConnInfo{
+			RemoteAddr: conn.RemoteAddr().String(),
+			Request:    r,
+			Codec:       codecName,
+			Compression: compression,
+		},
+		interceptors:     server.instrument("client", server.clientInterceptors),
+		server:           server,
+		available:        available,
 		callbackInc:      &incremental.Uint64{},
 		callbackChannels: make(map[uint64]chan *angoInMsg),
+		streams:          make(map[uint64]*angoStream),
+		receivers:        make(map[uint64]*angoReceiver),
 	}
 
+	// register client, refuse when shutdown started during setup
+	if !server.addClient(client) {
+		client.closeGoingAway()
+		return
+	}
+	defer server.removeClient(client)
+
 	// create session on server
+	server.logger().Infof("starting session for %s", client.info.RemoteAddr)
+	if server.Metrics != nil {
+		server.Metrics.SessionStarted()
+		defer server.Metrics.SessionStopped()
+	}
 	session := server.NewSession(client)
 	
 	// run protocol
-	err = runProtocol(conn, client, session)
+	err = server.runProtocol(ctx, conn, client, session)
+	if client.isClosing() {
+		err = ErrServerShutdown
+	}
+	// fail pending client calls, stop streams and wait for stream procedures to return
+	close(client.done)
+	client.closeCallbacks()
+	client.closeStreams()
+	client.calls.Wait()
+	server.logger().Infof("stopping session for %s: %v", client.info.RemoteAddr, err)
 	// err can be nil, but we want to call .Stop always
 	session.Stop(err)
 }
 
-func runProtocol(conn *websocket.Conn, client *Client, session Session) error {
+// readMessage reads the next message from the client. The read limit on the connection applies to the
+// (compressed) frames, so the size of the decompressed message is limited here.
+func (server *Server) readMessage(conn *websocket.Conn) ([]byte, error) {
+	_, r, err := conn.NextReader()
+	if err != nil {
+		return nil, err
+	}
+	if server.MaxMessageSize <= 0 {
+		return io.ReadAll(r)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, server.MaxMessageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > server.MaxMessageSize {
+		return nil, websocket.ErrReadLimit
+	}
+	return data, nil
+}
+
+func (server *Server) runProtocol(ctx context.Context, conn *websocket.Conn, client *Client, session Session) error {
+	interceptors := server.instrument("server", server.interceptors)
+
+	var limiter *tokenBucket
+	if server.RequestRate > 0 {
+		limiter = newTokenBucket(server.RequestRate, server.RequestBurst)
+	}
+
 	for {
 		// unmarshal root message structure
+		data, err := server.readMessage(conn)
+		if err != nil {
+			if err == websocket.ErrReadLimit {
+				server.limitExceeded(client.info, ErrMessageTooLarge)
+				return ErrMessageTooLarge
+			}
+			return err
+		}
 		inMsg := &angoInMsg{}
-		err := conn.ReadJSON(inMsg)
+		err = client.codec.unmarshal(data, inMsg)
 		if err != nil {
 			return err
 		}
 
-		switch inMsg.Type {
-		case msgTypeRequest:
-			fmt.Printf("Have request: %s\n", inMsg.Procedure)
-			switch inMsg.Procedure {
-			{{range .Service.ServerProcedures}}
-				case "{{.Name}}":
-					{{/* unmarshal procedure arguments */}}
-					procArgs := &angoServerArgsData{{.CapitalizedName}}{} {{/* var procArgs is referenced by .GoCallArgs */}}
-					err = json.Unmarshal(inMsg.Data, procArgs)
-					if err != nil {
-						return err
-					}
+		replies := &angoReplies{}
+		if inMsg.Type == msgTypeBatch {
+			err = server.handleBatch(ctx, client, session, interceptors, limiter, inMsg, replies)
+		} else {
+			err = server.handleMsg(ctx, client, session, interceptors, limiter, inMsg, replies)
+		}
+		if err == nil {
+			err = client.writeReplies(replies)
+		}
+		for i := 0; i < replies.calls; i++ {
+			client.endCall()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
 
-					{{/* prepare for return values */}}
-					{{if not .Oneway}}
-						procRets := &angoServerRetsData{{.CapitalizedName}}{} {{/* var procRets is referenced by .GoCallRets */}}
-						var procErr error {{/* var procErr is referenced by .GoCallRets */}}
-					{{end}}
-
-					{{/* call procedure, accept return values when not oneway */}}
-					{{if not .Oneway}}{{.GoCallRets}} = {{end}}session.{{.CapitalizedName}}( {{.GoCallArgs}} )
-
-					{{/* return message with procedure return values */}}
-					{{if not .Oneway}}
-						outMsg := &angoOutMsg{
-							Type:       "res",
-							CallbackID: inMsg.CallbackID,
-						}
-						if procErr != nil {
-							outMsg.Error = &angoOutError{
-								Type: "errorReturned",
-								Message: procErr.Error(),
-							}
-							err = conn.WriteJSON(outMsg)
-							if err != nil {
-								return err
-							}
-							break
-						}
-						outMsg.Data = procRets
-						err = conn.WriteJSON(outMsg)
-						if err != nil {
-							return err
-						}
-					{{end}}
-			{{end}}
-			default:
-				return ErrUnknownProcedure
+// angoReplies collects the replies to an incomming message or batch, they are written together.
+type angoReplies struct {
+	msgs  []*angoOutMsg
+	calls int // number of calls registered with beginCall, they end after the replies are written
+}
+
+func (r *angoReplies) add(outMsg *angoOutMsg) {
+	r.msgs = append(r.msgs, outMsg)
+}
+
+// writeReplies writes the replies, multiple replies are written as a single batch message.
+func (c *Client) writeReplies(r *angoReplies) error {
+	switch len(r.msgs) {
+	case 0:
+		return nil
+	case 1:
+		return c.writeMsg(r.msgs[0])
+	}
+	return c.writeMsg(&angoOutMsg{
+		Type: msgTypeBatch,
+		Data: r.msgs,
+	})
+}
+
+// handleBatch handles all messages in a batch message, in order.
+func (server *Server) handleBatch(ctx context.Context, client *Client, session Session, interceptors []Interceptor, limiter *tokenBucket, inMsg *angoInMsg, replies *angoReplies) error {
+	batch := []*angoInMsg{}
+	err := client.codec.unmarshal(inMsg.Data, &batch)
+	if err != nil {
+		return err
+	}
+	for _, msg := range batch {
+		// batches can't be nested
+		if msg == nil || msg.Type == msgTypeBatch {
+			return ErrInvalidMessageType
+		}
+		err = server.handleMsg(ctx, client, session, interceptors, limiter, msg, replies)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleMsg handles a single message from the client, replies are added to replies.
+func (server *Server) handleMsg(ctx context.Context, client *Client, session Session, interceptors []Interceptor, limiter *tokenBucket, inMsg *angoInMsg, replies *angoReplies) error {
+	switch inMsg.Type {
+	case msgTypeRequest:
+		if limiter != nil && !limiter.take() {
+			server.limitExceeded(client.info, ErrRateLimited)
+			if server.LimitAction == LimitActionDisconnect {
+				return ErrRateLimited
 			}
-		case msgTypeResponse:
-			callbackCh := client.callbackChannels[inMsg.CallbackID]
-			if callbackCh == nil {
-				return ErrInvalidCallbackID
+			if inMsg.CallbackID != 0 {
+				replies.add(&angoOutMsg{
+					Type:       "res",
+					CallbackID: inMsg.CallbackID,
+					Error:      &angoOutError{
+						Type:    "rateLimited",
+						Message: ErrRateLimited.Error(),
+					},
+				})
 			}
-			delete(client.callbackChannels, inMsg.CallbackID)
+			return nil
+		}
+		if !client.available.server(inMsg.Procedure) {
+			if inMsg.CallbackID != 0 {
+				replies.add(&angoOutMsg{
+					Type:       "res",
+					CallbackID: inMsg.CallbackID,
+					Error:      &angoOutError{
+						Type:    "procedureUnavailable",
+						Message: ErrProcedureUnavailable.Error(),
+					},
+				})
+			}
+			return nil
+		}
+		if !client.beginCall() {
+			// server is going away, don't accept new calls
+			if inMsg.CallbackID != 0 {
+				replies.add(&angoOutMsg{
+					Type:       "res",
+					CallbackID: inMsg.CallbackID,
+					Error:      &angoOutError{
+						Type:    "goingAway",
+						Message: ErrServerShutdown.Error(),
+					},
+				})
+			}
+			return nil
+		}
+		replies.calls++
+		return server.handleRequest(ctx, client, session, interceptors, inMsg, replies)
+	case msgTypeResponse:
+		client.callbackLock.Lock()
+		callbackCh := client.callbackChannels[inMsg.CallbackID]
+		delete(client.callbackChannels, inMsg.CallbackID)
+		client.callbackLock.Unlock()
+		if callbackCh == nil {
+			return ErrInvalidCallbackID
+		}
+		server.pendingClientCalls(-1)
 
-			callbackCh <- inMsg
-		default:
-			return ErrInvalidMessageType
+		callbackCh <- inMsg
+	case msgTypeStreamItem, msgTypeStreamEnd:
+		receiver := client.receiver(inMsg.CallbackID)
+		if receiver == nil {
+			// stream was cancelled, ignore items that were already underway
+			return nil
+		}
+		if !receiver.push(inMsg) {
+			return ErrStreamOverflow
+		}
+	case msgTypeStreamCancel:
+		stream := client.stream(inMsg.CallbackID)
+		if stream != nil {
+			stream.cancel()
+		}
+	case msgTypeStreamCredit:
+		creditData := &angoCreditData{}
+		err := client.codec.unmarshal(inMsg.Data, creditData)
+		if err != nil {
+			return err
+		}
+		stream := client.stream(inMsg.CallbackID)
+		if stream != nil {
+			stream.addCredit(creditData.Credit)
+		}
+	default:
+		return ErrInvalidMessageType
+	}
+	return nil
+}
+
+// handleRequest calls the server procedure requested by inMsg and adds the response to replies.
+func (server *Server) handleRequest(ctx context.Context, client *Client, session Session, interceptors []Interceptor, inMsg *angoInMsg, replies *angoReplies) error {
+	server.logger().Debugf("have request: %s", inMsg.Procedure)
+	if inMsg.Meta != nil {
+		ctx = context.WithValue(ctx, metaContextKey{}, inMsg.Meta)
+	}
+	switch inMsg.Procedure {
+	{{range .Service.ServerProcedures}}
+		case "{{.Name}}":
+			{{/* unmarshal procedure arguments */}}
+			procArgs := &angoServerArgsData{{.CapitalizedName}}{}
+			err := client.codec.unmarshal(inMsg.Data, procArgs)
+			if err != nil {
+				return err
+			}
+
+			{{/* call procedure through the interceptor chain */}}
+			call := &CallInfo{
+				Procedure: "{{.Name}}",
+				Args:      procArgs,
+				Conn:      client.info,
+				Meta:      inMsg.Meta,
+			}
+			{{if .Stream}}
+				stream := client.newStream(ctx, inMsg.CallbackID)
+				if stream == nil {
+					return ErrInvalidCallbackID
+				}
+				{{/* stream procedures run in their own goroutine, the protocol must keep handling credit and cancel messages */}}
+				client.calls.Add(1)
+				go func() {
+					defer client.calls.Done()
+					_, procErr := chainInterceptors(interceptors, func(ctx context.Context, call *CallInfo) (interface{}, error) {
+						{{if .Args}}procArgs := call.Args.(*angoServerArgsData{{.CapitalizedName}}){{end}} {{/* var procArgs is referenced by .GoCallArgs */}}
+						return nil, session.{{.CapitalizedName}}( {{.GoCallArgs}}{{if .Args}}, {{end}}&{{.CapitalizedName}}Stream{ctx: ctx, stream: stream} )
+					})(stream.ctx, call)
+					stream.end(procErr)
+				}()
+				return nil
+			{{else if .Subscribe}}
+				stream := client.newStream(ctx, inMsg.CallbackID)
+				if stream == nil {
+					return ErrInvalidCallbackID
+				}
+				sub := &angoSubscription{stream: stream}
+				_, procErr := chainInterceptors(interceptors, func(ctx context.Context, call *CallInfo) (interface{}, error) {
+					{{if .Args}}procArgs := call.Args.(*angoServerArgsData{{.CapitalizedName}}){{end}} {{/* var procArgs is referenced by .GoCallArgs */}}
+					return nil, session.{{.CapitalizedName}}( {{.GoCallArgs}}{{if .Args}}, {{end}}&{{.CapitalizedName}}Subscription{ctx: stream.ctx, sub: sub} )
+				})(stream.ctx, call)
+				if procErr != nil {
+					stream.end(procErr)
+					return nil
+				}
+				{{/* the subscription sends pending values and ends the stream in its own goroutine */}}
+				client.calls.Add(1)
+				go func() {
+					defer client.calls.Done()
+					sub.run()
+				}()
+				return nil
+			{{else}}
+			{{if not .Oneway}}procRetsData, procErr := {{end}}chainInterceptors(interceptors, func(ctx context.Context, call *CallInfo) (interface{}, error) {
+				{{if .Args}}procArgs := call.Args.(*angoServerArgsData{{.CapitalizedName}}){{end}} {{/* var procArgs is referenced by .GoCallArgs */}}
+				{{if .Oneway}}
+					session.{{.CapitalizedName}}( {{.GoCallArgs}} )
+					return nil, nil
+				{{else}}
+					procRets := &angoServerRetsData{{.CapitalizedName}}{} {{/* var procRets is referenced by .GoCallRets */}}
+					var procErr error {{/* var procErr is referenced by .GoCallRets */}}
+					{{.GoCallRets}} = session.{{.CapitalizedName}}( {{.GoCallArgs}} )
+					return procRets, procErr
+				{{end}}
+			})(ctx, call)
+
+			{{/* return message with procedure return values */}}
+			{{if not .Oneway}}
+				outMsg := &angoOutMsg{
+					Type:       "res",
+					CallbackID: inMsg.CallbackID,
+				}
+				if procErr != nil {
+					outMsg.Error = &angoOutError{
+						Type: "errorReturned",
+						Message: procErr.Error(),
+					}
+					replies.add(outMsg)
+					return nil
+				}
+				outMsg.Data = procRetsData
+				replies.add(outMsg)
+				return nil
+			{{else}}
+				return nil
+			{{end}}
+			{{end}}
+	{{end}}
+	}
+	return ErrUnknownProcedure
+}
+
+// angoAvailability holds the procedures that can be called on a connection where the protocol versions differ.
+// A procedure is available when it has the same signature on both sides.
+type angoAvailability struct {
+	serverProcs map[string]bool
+	clientProcs map[string]bool
+	unavailable int
+}
+
+// server returns whether the client may call the server procedure, a nil availability allows all procedures.
+func (a *angoAvailability) server(procedure string) bool {
+	return a == nil || a.serverProcs[procedure]
+}
+
+// client returns whether the client procedure may be called, a nil availability allows all procedures.
+func (a *angoAvailability) client(procedure string) bool {
+	return a == nil || a.clientProcs[procedure]
+}
+
+// parseAngoSignatures parses a signature set, formatted as "service=name;server=proc:sig,...;client=proc:sig,...".
+func parseAngoSignatures(set string) (service string, sections map[string]map[string]string) {
+	sections = make(map[string]map[string]string)
+	for _, section := range strings.Split(set, ";") {
+		name, list, _ := strings.Cut(section, "=")
+		if name == "service" {
+			service = list
+			continue
+		}
+		sigs := make(map[string]string)
+		for _, entry := range strings.Split(list, ",") {
+			procedure, sig, ok := strings.Cut(entry, ":")
+			if ok {
+				sigs[procedure] = sig
+			}
+		}
+		sections[name] = sigs
+	}
+	return service, sections
+}
+
+// negotiateProcedures compares the signature set offered by the client with angoSignatures.
+// It returns nil when the offer is for another service.
+func negotiateProcedures(offer string) *angoAvailability {
+	ownService, own := parseAngoSignatures(angoSignatures)
+	offerService, offered := parseAngoSignatures(offer)
+	if offerService != ownService {
+		return nil
+	}
+	a := &angoAvailability{
+		serverProcs: make(map[string]bool),
+		clientProcs: make(map[string]bool),
+	}
+	for section, procs := range map[string]map[string]bool{"server": a.serverProcs, "client": a.clientProcs} {
+		for procedure, sig := range own[section] {
+			if offered[section][procedure] == sig {
+				procs[procedure] = true
+			} else {
+				a.unavailable++
+			}
 		}
 	}
+	return a
 }
 
 // Client is a reference to the client connection and provides methods to call the client procedures.
 type Client struct {
 	ws               *websocket.Conn
+	codec            codec
+	ctx              context.Context
+	done             chan struct{} // closed when the protocol loop ended, the connection is closed
+	info             *ConnInfo
+	interceptors     []Interceptor
+	server           *Server
+	available        *angoAvailability // nil when the protocol versions match, all procedures are available
 	callbackInc      *incremental.Uint64
+	callbackLock     sync.Mutex
 	callbackChannels map[uint64]chan *angoInMsg
+	callbacksClosed  bool // set when the connection closed, no new callbacks are registered
+	writeLock        sync.Mutex
+
+	// streamLock guards streams and receivers
+	streamLock       sync.Mutex
+	streams          map[uint64]*angoStream   // server stream and subscribe procedure calls, by callback ID
+	receivers        map[uint64]*angoReceiver // client stream procedure calls, by callback ID
+
+	// callLock guards closing and calls
+	callLock         sync.Mutex
+	closing          bool
+	calls            sync.WaitGroup
+}
+
+// writeMsg encodes a message with the connection's codec and writes it to the websocket, it is safe for concurrent use.
+func (c *Client) writeMsg(v interface{}) error {
+	data, err := c.codec.marshal(v)
+	if err != nil {
+		return err
+	}
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	if c.info.Compression {
+		c.ws.EnableWriteCompression(len(data) >= c.server.compressionThreshold())
+	}
+	return c.ws.WriteMessage(c.codec.messageType(), data)
+}
+
+// beginCall registers an incomming call that is being handled.
+// It returns false when the client is closing and no new calls are accepted.
+func (c *Client) beginCall() bool {
+	c.callLock.Lock()
+	defer c.callLock.Unlock()
+	if c.closing {
+		return false
+	}
+	c.calls.Add(1)
+	return true
+}
+
+// endCall marks a call registered with beginCall as done.
+func (c *Client) endCall() {
+	c.calls.Done()
+}
+
+func (c *Client) isClosing() bool {
+	c.callLock.Lock()
+	defer c.callLock.Unlock()
+	return c.closing
+}
+
+// goAway notifies the client that the server is going away, waits for running calls to finish and closes the connection.
+func (c *Client) goAway() {
+	c.callLock.Lock()
+	c.closing = true
+	c.callLock.Unlock()
+
+	_ = c.writeMsg(&angoOutMsg{Type: msgTypeGoingAway})
+	c.cancelStreams()
+	c.calls.Wait()
+	c.closeGoingAway()
+}
+
+// closeGoingAway closes the websocket with the "going away" close code.
+func (c *Client) closeGoingAway() {
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutdown")
+	_ = c.ws.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+	c.ws.Close()
+}
+
+// streamWindow is the number of items a stream producer may send before it needs credit from the consumer.
+// The consumer grants new credit after it has consumed half of the window.
+const streamWindow = 16
+
+// angoCreditData is the data for a msgTypeStreamCredit message.
+type angoCreditData struct {
+	Credit int `json:"credit"`
+}
+
+// decodeError decodes the error field from a message.
+// The error can be a string or an object holding a message.
+func (c *Client) decodeError(raw json.RawMessage) error {
+	var errStr string
+	if c.codec.unmarshal(raw, &errStr) == nil {
+		return errors.New(errStr)
+	}
+	errObj := &angoOutError{}
+	err := c.codec.unmarshal(raw, errObj)
+	if err != nil {
+		return err
+	}
+	return errors.New(errObj.Message)
+}
+
+// angoStream is the producing side of a stream or subscribe procedure call made by the client.
+type angoStream struct {
+	client *Client
+	id     uint64
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	lock   sync.Mutex
+	credit int
+	notify chan struct{} // receives when credit is added
+}
+
+// newStream registers a new stream for the server stream or subscribe procedure call with given id.
+// It returns nil when a stream with the id already exists.
+func (c *Client) newStream(ctx context.Context, id uint64) *angoStream {
+	s := &angoStream{
+		client: c,
+		id:     id,
+		credit: streamWindow,
+		notify: make(chan struct{}, 1),
+	}
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	c.streamLock.Lock()
+	defer c.streamLock.Unlock()
+	if c.streams[id] != nil {
+		s.cancel()
+		return nil
+	}
+	c.streams[id] = s
+	return s
+}
+
+// stream returns the stream with given id, or nil when the stream doesn't exist (anymore).
+func (c *Client) stream(id uint64) *angoStream {
+	c.streamLock.Lock()
+	defer c.streamLock.Unlock()
+	return c.streams[id]
+}
+
+// addCredit allows the stream to send n more items.
+func (s *angoStream) addCredit(n int) {
+	s.lock.Lock()
+	s.credit += n
+	s.lock.Unlock()
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// takeCredit uses one credit, it returns false when the stream has no credit left.
+func (s *angoStream) takeCredit() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.credit == 0 {
+		return false
+	}
+	s.credit--
+	return true
+}
+
+// send sends an item, it waits for credit when the stream has none left.
+func (s *angoStream) send(ctx context.Context, item interface{}) error {
+	for !s.takeCredit() {
+		select {
+		case <-s.notify:
+		case <-ctx.Done():
+			return ErrStreamCancelled
+		}
+	}
+	if ctx.Err() != nil {
+		return ErrStreamCancelled
+	}
+	return s.writeItem(item)
+}
+
+// writeItem writes an item message for the stream.
+func (s *angoStream) writeItem(item interface{}) error {
+	return s.client.writeMsg(&angoOutMsg{
+		Type:       msgTypeStreamItem,
+		CallbackID: s.id,
+		Data:       item,
+	})
+}
+
+// end unregisters the stream and sends the end of the stream to the client.
+func (s *angoStream) end(err error) {
+	s.cancel()
+	s.client.streamLock.Lock()
+	delete(s.client.streams, s.id)
+	s.client.streamLock.Unlock()
+	outMsg := &angoOutMsg{
+		Type:       msgTypeStreamEnd,
+		CallbackID: s.id,
+	}
+	if err != nil {
+		outMsg.Error = &angoOutError{
+			Type:    "errorReturned",
+			Message: err.Error(),
+		}
+	}
+	_ = s.client.writeMsg(outMsg)
+}
+
+// angoSubscription is the publishing side of a subscribe procedure call made by the client.
+// It uses the stream messages and flow control, but never blocks: values that can't be sent
+// for lack of credit are replaced by newer values.
+type angoSubscription struct {
+	stream *angoStream
+
+	lock    sync.Mutex // guards pending, closed and err, held while writing to keep values in order
+	pending interface{} // latest value that was not sent yet
+	closed  bool        // set when the subscription was closed by the server
+	err     error       // error given to close
+}
+
+// subscriptionFlushTimeout is the time a subscription closed by the server waits for credit to send its pending value.
+const subscriptionFlushTimeout = 5 * time.Second
+
+// publish sends value when the stream has credit, or keeps it as pending value.
+func (s *angoSubscription) publish(value interface{}) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.stream.ctx.Err() != nil {
+		return ErrUnsubscribed
+	}
+	if !s.stream.takeCredit() {
+		s.pending = value
+		return nil
+	}
+	s.pending = nil
+	return s.stream.writeItem(value)
+}
+
+// close ends the subscription with err.
+func (s *angoSubscription) close(err error) {
+	s.lock.Lock()
+	if !s.closed {
+		s.closed = true
+		s.err = err
+	}
+	s.lock.Unlock()
+	s.stream.cancel()
+}
+
+// run sends the pending value when credit is added, until the subscription ends.
+// It ends the stream when the client unsubscribed, the subscription was closed or the connection closed.
+// When the subscription was closed by the server, the pending value is sent before the end of the stream.
+// The pending value is discarded when the client unsubscribed or the connection closed.
+func (s *angoSubscription) run() {
+	for {
+		select {
+		case <-s.stream.notify:
+			s.lock.Lock()
+			if s.pending != nil && s.stream.ctx.Err() == nil && s.stream.takeCredit() {
+				_ = s.stream.writeItem(s.pending)
+				s.pending = nil
+			}
+			s.lock.Unlock()
+		case <-s.stream.ctx.Done():
+			s.lock.Lock()
+			err, pending, closed := s.err, s.pending, s.closed
+			s.pending = nil
+			s.lock.Unlock()
+			if closed && pending != nil {
+				s.flush(pending)
+			}
+			s.stream.end(err)
+			return
+		}
+	}
+}
+
+// flush sends the pending value of a subscription closed by the server. It waits for credit, but
+// gives up when the connection closes or no credit was granted within subscriptionFlushTimeout.
+func (s *angoSubscription) flush(pending interface{}) {
+	timeout := time.NewTimer(subscriptionFlushTimeout)
+	defer timeout.Stop()
+	for !s.stream.takeCredit() {
+		select {
+		case <-s.stream.notify:
+		case <-s.stream.client.done:
+			return
+		case <-timeout.C:
+			return
+		}
+	}
+	_ = s.stream.writeItem(pending)
+}
+
+// angoReceiver is the consuming side of a stream procedure call made to the client.
+type angoReceiver struct {
+	client   *Client
+	id       uint64
+	items    chan *angoInMsg // item and end messages, buffered for a full window
+	consumed int             // items consumed since credit was last granted
+	err      error           // set when the stream has ended
+}
+
+// newReceiver registers a new receiver for a client stream procedure call.
+func (c *Client) newReceiver() *angoReceiver {
+	r := &angoReceiver{
+		client: c,
+		id:     c.callbackInc.Next(),
+		items:  make(chan *angoInMsg, streamWindow+1),
+	}
+	c.streamLock.Lock()
+	c.receivers[r.id] = r
+	c.streamLock.Unlock()
+	return r
+}
+
+// receiver returns the receiver with given id, or nil when the receiver doesn't exist (anymore).
+func (c *Client) receiver(id uint64) *angoReceiver {
+	c.streamLock.Lock()
+	defer c.streamLock.Unlock()
+	return c.receivers[id]
+}
+
+// remove unregisters the receiver.
+func (r *angoReceiver) remove() {
+	r.client.streamLock.Lock()
+	delete(r.client.receivers, r.id)
+	r.client.streamLock.Unlock()
+}
+
+// push adds an item or end message for the receiver. It returns false when the producer didn't respect the stream window.
+func (r *angoReceiver) push(inMsg *angoInMsg) bool {
+	if inMsg.Type == msgTypeStreamEnd {
+		r.remove()
+	}
+	select {
+	case r.items <- inMsg:
+		return true
+	default:
+		return false
+	}
+}
+
+// recv decodes the next item into v. It returns io.EOF when the stream has ended normally.
+func (r *angoReceiver) recv(v interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	inMsg, ok := <-r.items
+	if !ok {
+		r.err = ErrConnectionClosed
+		return r.err
+	}
+	if inMsg.Type == msgTypeStreamEnd {
+		r.err = io.EOF
+		if inMsg.Error != nil {
+			r.err = r.client.decodeError(inMsg.Error)
+		}
+		return r.err
+	}
+	r.consumed++
+	if r.consumed >= streamWindow/2 {
+		err := r.client.writeMsg(&angoOutMsg{
+			Type:       msgTypeStreamCredit,
+			CallbackID: r.id,
+			Data:       &angoCreditData{Credit: r.consumed},
+		})
+		if err != nil {
+			return err
+		}
+		r.consumed = 0
+	}
+	return r.client.codec.unmarshal(inMsg.Data, v)
+}
+
+// cancel cancels the stream, the client is asked to stop producing items.
+func (r *angoReceiver) cancel() {
+	if r.err != nil {
+		return
+	}
+	r.err = ErrStreamCancelled
+	r.remove()
+	_ = r.client.writeMsg(&angoOutMsg{
+		Type:       msgTypeStreamCancel,
+		CallbackID: r.id,
+	})
+}
+
+// fail ends the receiver with given error.
+func (r *angoReceiver) fail(err error) {
+	r.err = err
+	r.remove()
+}
+
+// closeStreams cancels all server streams and ends all receivers, it is called when the connection has closed.
+func (c *Client) closeStreams() {
+	c.streamLock.Lock()
+	defer c.streamLock.Unlock()
+	for _, s := range c.streams {
+		s.cancel()
+	}
+	for id, r := range c.receivers {
+		close(r.items)
+		delete(c.receivers, id)
+	}
+}
+
+// cancelStreams cancels all server streams.
+func (c *Client) cancelStreams() {
+	c.streamLock.Lock()
+	defer c.streamLock.Unlock()
+	for _, s := range c.streams {
+		s.cancel()
+	}
+}
+
+// callStream runs the client interceptor chain for call, ending with starting the stream procedure on the client.
+// Items are received by receiver.
+func (c *Client) callStream(call *CallInfo, receiver *angoReceiver) error {
+	_, err := chainInterceptors(c.interceptors, func(ctx context.Context, call *CallInfo) (interface{}, error) {
+		if !c.available.client(call.Procedure) {
+			return nil, ErrProcedureUnavailable
+		}
+		return nil, c.writeMsg(&angoOutMsg{
+			Type:       msgTypeRequest,
+			Procedure:  call.Procedure,
+			CallbackID: receiver.id,
+			Data:       call.Args,
+			Meta:       call.Meta,
+		})
+	})(c.ctx, call)
+	return err
+}
+
+// ConnInfo returns information about the websocket connection to the client.
+func (c *Client) ConnInfo() *ConnInfo {
+	return c.info
+}
+
+// removeCallback removes the callback channel for a call to a client procedure that won't receive a response.
+func (c *Client) removeCallback(id uint64) {
+	c.callbackLock.Lock()
+	_, ok := c.callbackChannels[id]
+	delete(c.callbackChannels, id)
+	c.callbackLock.Unlock()
+	if ok {
+		c.server.pendingClientCalls(-1)
+	}
+}
+
+// closeCallbacks fails all calls to client procedures waiting for a response, it is called when the connection has closed.
+func (c *Client) closeCallbacks() {
+	c.callbackLock.Lock()
+	defer c.callbackLock.Unlock()
+	c.callbacksClosed = true
+	for id, callbackCh := range c.callbackChannels {
+		close(callbackCh)
+		delete(c.callbackChannels, id)
+		c.server.pendingClientCalls(-1)
+	}
+}
+
+// call runs the client interceptor chain for call, ending with the actual call to the client.
+// When rets is nil the call is oneway, otherwise call waits for the response and decodes the return values into rets.
+func (c *Client) call(call *CallInfo, rets interface{}) error {
+	_, err := chainInterceptors(c.interceptors, func(ctx context.Context, call *CallInfo) (interface{}, error) {
+		if !c.available.client(call.Procedure) {
+			return nil, ErrProcedureUnavailable
+		}
+		outMsg := angoOutMsg{
+			Type:      "req",
+			Procedure: call.Procedure,
+			Data:      call.Args,
+			Meta:      call.Meta,
+		}
+
+		if rets == nil {
+			// oneway, only write message
+			return nil, c.writeMsg(outMsg)
+		}
+
+		// create callback channel
+		callbackCh := make(chan *angoInMsg, 1)
+		outMsg.CallbackID = c.callbackInc.Next()
+		c.callbackLock.Lock()
+		if c.callbacksClosed {
+			c.callbackLock.Unlock()
+			return nil, ErrConnectionClosed
+		}
+		if max := c.server.MaxPendingClientCalls; max > 0 && len(c.callbackChannels) >= max {
+			c.callbackLock.Unlock()
+			c.server.limitExceeded(c.info, ErrTooManyPendingCalls)
+			if c.server.LimitAction == LimitActionDisconnect {
+				c.ws.Close()
+			}
+			return nil, ErrTooManyPendingCalls
+		}
+		c.callbackChannels[outMsg.CallbackID] = callbackCh
+		c.callbackLock.Unlock()
+		c.server.pendingClientCalls(1)
+
+		// write message
+		err := c.writeMsg(outMsg)
+		if err != nil {
+			c.removeCallback(outMsg.CallbackID)
+			return nil, err
+		}
+
+		// wait for response message, the channel is closed when the connection closed
+		var respMsg *angoInMsg
+		select {
+		case respMsg = <-callbackCh:
+		case <-c.ctx.Done():
+		}
+		if respMsg == nil {
+			c.removeCallback(outMsg.CallbackID)
+			return nil, ErrConnectionClosed
+		}
+
+		// check for error
+		if respMsg.Error != nil {
+			return nil, c.decodeError(respMsg.Error)
+		}
+		err = c.codec.unmarshal(respMsg.Data, rets)
+		if err != nil {
+			return nil, err
+		}
+		return rets, nil
+	})(c.ctx, call)
+	return err
 }
 
 {{range .Service.ClientProcedures}}
@@ -272,24 +1857,68 @@ type Client struct {
 		// {{.CapitalizedName}} is a ango procedure defined in the .ango file.
 		// This is a oneway procedure, it will return immediatly after the call has been sent to the client.
 		func (c *Client) {{.CapitalizedName}}( {{.GoArgs}} )( err error ) {
-			fmt.Println("Called oneway service {{.CapitalizedName}}")
-			outMsg := angoOutMsg{
-				Type:      "req",
+			c.server.logger().Debugf("called oneway client procedure {{.Name}}")
+			call := &CallInfo{
 				Procedure: "{{.Name}}",
-				Data:      &angoClientArgsData{{.CapitalizedName}}{
+				Args:      &angoClientArgsData{{.CapitalizedName}}{
 				{{range .Args}}
 					{{.CapitalizedName}}: {{.Name}},{{end}}
 				},
+				Conn:      c.info,
 			}
+			return c.call(call, nil)
+		}
+	{{else if .Stream}}
+		// {{.CapitalizedName}}Item contains the values for a single item of the stream procedure Client.{{.CapitalizedName}}.
+		type {{.CapitalizedName}}Item struct {
+			{{range .Rets}}
+				{{.CapitalizedName}} {{.GoTypeName}}{{end}}
+		}
+
+		// {{.CapitalizedName}}Receiver receives the items for a call to the stream procedure Client.{{.CapitalizedName}}.
+		type {{.CapitalizedName}}Receiver struct {
+			receiver *angoReceiver
+		}
 
-			// write message
-			err = c.ws.WriteJSON(outMsg)
+		// Recv waits for and returns the next item. Recv returns io.EOF when the stream has ended normally.
+		// Any other error indicates the stream failed, or the procedure returned with an error.
+		// Recv is not safe for concurrent use.
+		func (r *{{.CapitalizedName}}Receiver) Recv() (*{{.CapitalizedName}}Item, error) {
+			retsData := &angoClientRetsData{{.CapitalizedName}}{}
+			err := r.receiver.recv(retsData)
 			if err != nil {
-				return {{/* when service is not oneway, this will return the error using named return values */}}
+				return nil, err
 			}
+			return &{{.CapitalizedName}}Item{
+				{{range .Rets}}
+					{{.CapitalizedName}}: retsData.{{.CapitalizedName}},{{end}}
+			}, nil
+		}
 
-			// all done
-			return
+		// Cancel cancels the stream. Recv returns ErrStreamCancelled after Cancel was called.
+		// Cancel must not be called concurrently with Recv.
+		func (r *{{.CapitalizedName}}Receiver) Cancel() {
+			r.receiver.cancel()
+		}
+
+		// {{.CapitalizedName}} is a ango stream procedure defined in the .ango file.
+		// Items produced by the client are received with the returned {{.CapitalizedName}}Receiver.
+		func (c *Client) {{.CapitalizedName}}( {{.GoArgs}} ) *{{.CapitalizedName}}Receiver {
+			c.server.logger().Debugf("called stream client procedure {{.Name}}")
+			call := &CallInfo{
+				Procedure: "{{.Name}}",
+				Args:      &angoClientArgsData{{.CapitalizedName}}{
+				{{range .Args}}
+					{{.CapitalizedName}}: {{.Name}},{{end}}
+				},
+				Conn:      c.info,
+			}
+			receiver := c.newReceiver()
+			err := c.callStream(call, receiver)
+			if err != nil {
+				receiver.fail(err)
+			}
+			return &{{.CapitalizedName}}Receiver{receiver: receiver}
 		}
 	{{else}}
 		// {{.CapitalizedName}}Result contains the return values for Client.{{.CapitalizedName}}.
@@ -308,49 +1937,23 @@ type Client struct {
 			ch := make(chan *{{.CapitalizedName}}Result, 1)
 			retCh = ch
 			response := &{{.CapitalizedName}}Result{}
-			fmt.Println("Called returning service {{.CapitalizedName}}")
+			c.server.logger().Debugf("called returning client procedure {{.Name}}")
 			
 			go func() {
 				defer func() {
 					ch <- response
 				}()
 
-				outMsg := angoOutMsg{
-					Type:      "req",
+				call := &CallInfo{
 					Procedure: "{{.Name}}",
-					Data:      &angoClientArgsData{{.CapitalizedName}}{
+					Args:      &angoClientArgsData{{.CapitalizedName}}{
 					{{range .Args}}
 						{{.CapitalizedName}}: {{.Name}},{{end}}
 					},
-				}
-
-				// create callback channel
-				callbackCh := make(chan *angoInMsg, 1)
-				outMsg.CallbackID = c.callbackInc.Next()
-				c.callbackChannels[outMsg.CallbackID] = callbackCh
-
-				// write message
-				response.Err = c.ws.WriteJSON(outMsg)
-				if response.Err != nil {
-					return {{/* when service is not oneway, this will return the error using named return values */}}
-				}
-
-				// wait for response message
-				respMsg := <- callbackCh
-				close(callbackCh)
-
-				// check for error
-				if(respMsg.Error != nil) {
-					var errStr string
-					response.Err = json.Unmarshal(respMsg.Error, &errStr)
-					if response.Err != nil {
-						return
-					}
-					response.Err = errors.New(errStr)
-					return
+					Conn:      c.info,
 				}
 				retsData := &angoClientRetsData{{.CapitalizedName}}{}
-				response.Err = json.Unmarshal(respMsg.Data, retsData)
+				response.Err = c.call(call, retsData)
 				if response.Err != nil {
 					return
 				}
@@ -361,4 +1964,4 @@ type Client struct {
 			return
 		}
 	{{end}}
-{{end}}
\ No newline at end of file
+{{end}}
@@ -0,0 +1,960 @@
+// WARNING This is generated code by the ango tool (github.com/GeertJohan/ango)
+// DO NOT EDIT unless you know what you're doing!
+//
+// TypeScript client for the {{.Service.Name}} service. It has no dependencies and isn't tied to a framework:
+// it uses the global WebSocket (browsers, Deno, Node 22+) or the WebSocket implementation given in the options.
+//
+// Values are encoded as JSON, like encoding/json encodes the Go values:
+// struct fields are capitalized, []uint8 values are base64 strings, and nil slices and maps are null.
+// Integers beyond 2^53 lose precision.
+
+export const serviceName = "{{.Service.Name}}";
+export const protocolVersion = "{{.ProtocolVersion}}";
+// signature of every procedure, procedures with the same signature on both sides can be called when the versions differ
+const signatures = "{{.SignatureSet}}";
+
+// number of stream items that may be sent before the receiving side must grant credit
+const streamWindow = 16;
+
+// TYPES, as defined in .ango file
+{{range .Service.Types}}{{if not .GoIsBuiltin}}
+{{.TsDeclaration}}
+{{end}}{{end}}
+// RETURN VALUES of the procedures
+{{range .Service.ServerProcedures}}{{if .Rets}}
+export interface {{.TsRetsName}} {
+{{- range .Rets}}
+	{{.Name}}: {{.TsTypeName}};
+{{- end}}
+}
+{{end}}{{end}}{{range .Service.ClientProcedures}}{{if .Rets}}
+export interface {{.TsRetsName}} {
+{{- range .Rets}}
+	{{.Name}}: {{.TsTypeName}};
+{{- end}}
+}
+{{end}}{{end}}
+// ERRORS
+// Calls are rejected, and streams and subscriptions end, with an AngoError. Its type tells what went wrong.
+
+// Unknown is an error of a type that this client doesn't know.
+export interface Unknown {
+	type: "unknown";
+	message: string;
+}
+
+// ErrorReturned is the error returned by the procedure.
+export interface ErrorReturned {
+	type: "errorReturned";
+	message: string;
+}
+
+// GoingAway rejects calls that weren't handled before the server started shutting down.
+export interface GoingAway {
+	type: "goingAway";
+	message: string;
+}
+
+// RateLimited rejects calls that exceeded the rate limit of the server.
+export interface RateLimited {
+	type: "rateLimited";
+	message: string;
+}
+
+// ProcedureUnavailable rejects calls to procedures that differ between the client and server version.
+export interface ProcedureUnavailable {
+	type: "procedureUnavailable";
+	message: string;
+}
+
+// VersionMismatch rejects all calls when the server doesn't accept the version of this client.
+export interface VersionMismatch {
+	type: "versionMismatch";
+	message: string;
+}
+
+// ConnectionClosed rejects calls that were pending when the connection closed, and calls made after.
+export interface ConnectionClosed {
+	type: "connectionClosed";
+	message: string;
+}
+
+export type AngoError =
+	| Unknown
+	| ErrorReturned
+	| GoingAway
+	| RateLimited
+	| ProcedureUnavailable
+	| VersionMismatch
+	| ConnectionClosed;
+
+const errorTypes = ["unknown", "errorReturned", "goingAway", "rateLimited", "procedureUnavailable", "versionMismatch", "connectionClosed"];
+
+// isAngoError returns whether err is an AngoError, e.g. in a catch clause.
+export function isAngoError(err: unknown): err is AngoError {
+	if (typeof err != "object" || err === null) {
+		return false;
+	}
+	const e = err as { type?: unknown; message?: unknown };
+	return typeof e.type == "string" && errorTypes.indexOf(e.type) >= 0 && typeof e.message == "string";
+}
+
+const errVersionMismatch: VersionMismatch = { type: "versionMismatch", message: "version mismatch" };
+const errConnectionClosed: ConnectionClosed = { type: "connectionClosed", message: "connection closed" };
+const errGoingAway: GoingAway = { type: "goingAway", message: "server is going away" };
+const errProcedureUnavailable: ProcedureUnavailable = { type: "procedureUnavailable", message: "procedure unavailable" };
+
+// toAngoError converts the error field of a message. Clients of older versions send the error as string.
+function toAngoError(raw: unknown): AngoError {
+	if (typeof raw == "string") {
+		return { type: "errorReturned", message: raw };
+	}
+	if (isAngoError(raw)) {
+		return raw;
+	}
+	const message = typeof raw == "object" && raw !== null ? (raw as { message?: unknown }).message : undefined;
+	return { type: "unknown", message: typeof message == "string" ? message : "" };
+}
+
+// errorMessage returns the message for an error thrown or rejected by a handler.
+function errorMessage(err: unknown): string {
+	if (typeof err == "string") {
+		return err;
+	}
+	if (err instanceof Error) {
+		return err.message;
+	}
+	return String(err);
+}
+
+// OPTIONS
+
+export type Meta = { [key: string]: string };
+
+// CallOptions is the optional last argument for calls to server procedures.
+export interface CallOptions {
+	// meta is sent along with the request, e.g. { locale: "nl" }
+	meta?: Meta;
+}
+
+// HandlerOptions is given as last argument to the handlers for client procedures.
+export interface HandlerOptions {
+	// meta that was sent along with the request
+	meta: Meta;
+}
+
+// TraceContext holds W3C trace context values to send along with a request.
+export interface TraceContext {
+	traceparent?: string;
+	baggage?: string;
+}
+
+// WebSocketLike is the part of the WebSocket API used by the client.
+// The WebSocket class in browsers and Node, and the one from the ws package, satisfy it.
+export interface WebSocketLike {
+	readonly readyState: number;
+	send(data: string): void;
+	close(code?: number, reason?: string): void;
+	onopen: ((ev: any) => any) | null;
+	onmessage: ((ev: any) => any) | null;
+	onerror: ((ev: any) => any) | null;
+	onclose: ((ev: any) => any) | null;
+}
+
+export type WebSocketConstructor = new (url: string) => WebSocketLike;
+
+// STREAMS
+
+// Stream is returned by a call to a server stream procedure. Iterate the items with for await,
+// leaving the loop early cancels the stream. The loop throws an AngoError when the stream ends with an error.
+export interface Stream<T> extends AsyncIterable<T> {
+	// cancel stops the stream, the server stops sending items.
+	cancel(): void;
+}
+
+// Subscription is returned by a call to a server subscribe procedure.
+// The server sends the latest value; values that were replaced before they were sent are skipped.
+export interface Subscription<T> {
+	// value is the latest value, undefined until the first value arrived.
+	readonly value: T | undefined;
+	onUpdate(fn: (value: T) => void): Subscription<T>;
+	onError(fn: (err: AngoError) => void): Subscription<T>;
+	onEnd(fn: () => void): Subscription<T>;
+	unsubscribe(): void;
+}
+
+// StreamSender is given to the handler of a client stream procedure to send the items.
+export interface StreamSender<T> {
+	// send queues an item, it is sent when the server granted credit. It returns false when the stream was cancelled or ended.
+	send(item: T): boolean;
+	// end ends the stream after the queued items are sent, optionally with an error message.
+	end(error?: string): void;
+	// onCancel registers fn to be called when the server cancels the stream.
+	onCancel(fn: () => void): void;
+	isCancelled(): boolean;
+}
+
+// HANDLERS for the client procedures, as defined in .ango file.
+// A handler returns the result or a promise for it. A thrown error or rejection is sent to the server as errorReturned.
+export interface {{.Service.CapitalizedName}}Handlers {
+{{- range .Service.ClientProcedures}}
+{{- if .Stream}}
+	{{.Name}}({{.TsArgs}}{{if .Args}}, {{end}}stream: StreamSender<{{.TsRetsName}}>, options: HandlerOptions): void;
+{{- else if .Oneway}}
+	{{.Name}}({{.TsArgs}}{{if .Args}}, {{end}}options: HandlerOptions): void;
+{{- else}}
+	{{.Name}}({{.TsArgs}}{{if .Args}}, {{end}}options: HandlerOptions): {{if .Rets}}{{.TsRetsName}} | Promise<{{.TsRetsName}}>{{else}}void | Promise<void>{{end}};
+{{- end}}
+{{- end}}
+}
+
+export interface {{.Service.CapitalizedName}}ClientOptions {
+	// url of the websocket, e.g. "wss://example.com/websocket-ango-{{.Service.Name}}"
+	url: string;
+	handlers{{if not .Service.ClientProcedures}}?{{end}}: {{.Service.CapitalizedName}}Handlers;
+	// WebSocket is the WebSocket implementation to use, defaults to the global WebSocket.
+	WebSocket?: WebSocketConstructor;
+	// traceContext is called for every call to a server procedure, the values are sent as meta.
+	traceContext?: (procedure: string) => TraceContext | undefined;
+	// onGoingAway is called when the server is shutting down. Pending calls are still answered, new calls are rejected.
+	onGoingAway?: () => void;
+	// onClose is called when the connection closed.
+	onClose?: () => void;
+}
+
+// {{.Service.CapitalizedName}}Client is a connection to the {{.Service.Name}} service, it has a method for every server procedure.
+// Calls made before the connection is set up are sent once the server accepted the handshake.
+export class {{.Service.CapitalizedName}}Client {
+	private readonly conn: Connection;
+{{- if .Service.ClientProcedures}}
+	private readonly handlers: {{.Service.CapitalizedName}}Handlers | undefined;
+{{- end}}
+
+	// ready resolves when the server accepted the handshake, it rejects with a VersionMismatch or ConnectionClosed error.
+	readonly ready: Promise<void>;
+
+	constructor(options: {{.Service.CapitalizedName}}ClientOptions) {
+{{- if .Service.ClientProcedures}}
+		this.handlers = options.handlers;
+{{- end}}
+		this.conn = new Connection(options, (messageObj) => this.handleRequest(messageObj));
+		this.ready = this.conn.ready;
+	}
+
+	// close closes the connection, pending calls are rejected with a ConnectionClosed error.
+	close(): void {
+		this.conn.close();
+	}
+
+	// PROCEDURES, as defined in .ango file
+{{- range .Service.ServerProcedures}}
+{{if .Stream}}
+	{{.Name}}({{.TsArgs}}{{if .Args}}, {{end}}options?: CallOptions): Stream<{{.TsRetsName}}> {
+		return this.conn.stream<{{.TsRetsName}}>("{{.Name}}", {{.TsArgsData}}, options);
+	}
+{{- else if .Subscribe}}
+	{{.Name}}({{.TsArgs}}{{if .Args}}, {{end}}options?: CallOptions): Subscription<{{.TsSubscribeValue}}> {
+		return this.conn.subscribe<{{.TsSubscribeValue}}>("{{.Name}}", {{.TsArgsData}}, options{{if eq (len .Rets) 1}}, "{{(index .Rets 0).Name}}"{{end}});
+	}
+{{- else if .Oneway}}
+	// {{.Name}} is oneway, the promise resolves when the request was sent.
+	{{.Name}}({{.TsArgs}}{{if .Args}}, {{end}}options?: CallOptions): Promise<void> {
+		return this.conn.request("{{.Name}}", true, {{.TsArgsData}}, options);
+	}
+{{- else}}
+	{{.Name}}({{.TsArgs}}{{if .Args}}, {{end}}options?: CallOptions): Promise<{{if .Rets}}{{.TsRetsName}}{{else}}void{{end}}> {
+		return this.conn.request("{{.Name}}", false, {{.TsArgsData}}, options);
+	}
+{{- end}}
+{{- end}}
+
+	// handleRequest calls the handler for an incomming request
+	private handleRequest(messageObj: Message): void {
+{{- if .Service.ClientProcedures}}
+		const handlers = this.handlers;
+		switch (messageObj.procedure) {
+{{- range .Service.ClientProcedures}}
+		case "{{.Name}}":
+			if (handlers === undefined) {
+				break;
+			}
+{{- if .Stream}}
+			this.conn.handleStream(messageObj, (stream) => handlers.{{.Name}}({{.JsCallArgs}}{{if .Args}}, {{end}}stream, handlerOptions(messageObj)));
+{{- else if .Oneway}}
+			this.conn.handleOneway(messageObj, () => handlers.{{.Name}}({{.JsCallArgs}}{{if .Args}}, {{end}}handlerOptions(messageObj)));
+{{- else}}
+			this.conn.handleRequest(messageObj, () => handlers.{{.Name}}({{.JsCallArgs}}{{if .Args}}, {{end}}handlerOptions(messageObj)));
+{{- end}}
+			return;
+{{- end}}
+		}
+{{- end}}
+		this.conn.handleUnavailable(messageObj);
+	}
+}
+
+// PROTOCOL, the code below is the same for every service
+
+// Message is a message on the websocket, see notes/protocol.md
+interface Message {
+	type: string;
+	procedure?: string;
+	cb_id?: number;
+	data?: any;
+	error?: unknown;
+	meta?: Meta;
+}
+
+type State = "init" | "running" | "goingAway" | "stopped";
+
+// QueueItem is a message that waits until the handshake is done.
+// sent is called when the message was written, failed when the message will never be sent.
+interface QueueItem {
+	message: Message;
+	sent?: () => void;
+	failed?: (err: AngoError) => void;
+}
+
+interface Callback {
+	resolve: (data: any) => void;
+	reject: (err: AngoError) => void;
+}
+
+// StreamConsumer receives the items for a call to a server stream or subscribe procedure.
+interface StreamConsumer {
+	item(data: any): void;
+	end(err: AngoError | undefined): void;
+}
+
+// handlerOptions creates the options given as last argument to a handler.
+function handlerOptions(messageObj: Message): HandlerOptions {
+	if (typeof messageObj.meta == "object" && messageObj.meta !== null) {
+		return { meta: messageObj.meta };
+	}
+	return { meta: {} };
+}
+
+// parseSignatures parses a signature set into the signatures by section and procedure name.
+// A signature set is formatted as "service=name;server=proc:sig,...;client=proc:sig,...".
+function parseSignatures(set: string): { server: Map<string, string>; client: Map<string, string> } {
+	const sections = { server: new Map<string, string>(), client: new Map<string, string>() };
+	for (const part of set.split(";")) {
+		const [name, entries] = part.split("=");
+		const section = name == "server" ? sections.server : name == "client" ? sections.client : undefined;
+		if (section === undefined || !entries) {
+			continue;
+		}
+		for (const entry of entries.split(",")) {
+			const [proc, sig] = entry.split(":");
+			section.set(proc, sig);
+		}
+	}
+	return sections;
+}
+
+// Connection implements the protocol: handshake, requests and responses, streams and batches.
+class Connection {
+	readonly ready: Promise<void>;
+	private readonly ws: WebSocketLike;
+	private readonly options: {{.Service.CapitalizedName}}ClientOptions;
+	private readonly dispatch: (messageObj: Message) => void;
+	private state: State = "init";
+	private currentCallbackID = 0;
+	// pending requests, by cb_id
+	private readonly callbacks = new Map<number, Callback>();
+	// messages sent before the handshake is done
+	private queue: QueueItem[] = [];
+	// messages sent within the current tick, they are coalesced into a single batch message
+	private batch: QueueItem[] = [];
+	// consumers for server stream and subscribe procedure calls, by cb_id
+	private readonly streams = new Map<number, StreamConsumer>();
+	// producers for client stream procedure calls, by cb_id
+	private readonly producers = new Map<number, ClientStream<any>>();
+	// server procedures that can be called when the versions differ, undefined when all procedures can be called
+	private available: Set<string> | undefined;
+	// error for calls made after the connection stopped
+	private stopError: AngoError = errConnectionClosed;
+	private onCloseCalled = false;
+	private resolveReady: () => void = () => {};
+	private rejectReady: (err: AngoError) => void = () => {};
+
+	constructor(options: {{.Service.CapitalizedName}}ClientOptions, dispatch: (messageObj: Message) => void) {
+		this.options = options;
+		this.dispatch = dispatch;
+		this.ready = new Promise<void>((resolve, reject) => {
+			this.resolveReady = resolve;
+			this.rejectReady = reject;
+		});
+		// the rejection is also reported to the calls, it doesn't have to be handled
+		this.ready.catch(() => {});
+
+		const WebSocketImpl = options.WebSocket ?? ((globalThis as any).WebSocket as WebSocketConstructor | undefined);
+		if (WebSocketImpl === undefined) {
+			throw new Error("ango: no WebSocket implementation, set the WebSocket option");
+		}
+		this.ws = new WebSocketImpl(options.url);
+		this.ws.onopen = () => {
+			// send version string, followed by the codecs in order of preference and the signatures
+			this.ws.send(protocolVersion + " json " + signatures);
+		};
+		this.ws.onmessage = (ev) => this.receive(ev.data);
+		this.ws.onclose = () => this.closed();
+		// an error is followed by close
+		this.ws.onerror = () => {};
+	}
+
+	close(): void {
+		this.ws.close();
+		this.closed();
+	}
+
+	// request makes a call to a server procedure, oneway requests resolve when sent.
+	request(name: string, oneway: boolean, data: object, options: CallOptions | undefined): Promise<any> {
+		const err = this.callError(name);
+		if (err !== undefined) {
+			return Promise.reject(err);
+		}
+		return new Promise((resolve, reject) => {
+			const request = this.newRequest(name, data, options);
+			if (oneway) {
+				this.send({ message: request, sent: () => resolve(undefined), failed: reject });
+				return;
+			}
+			const id = this.callbackID();
+			request.cb_id = id;
+			this.callbacks.set(id, { resolve, reject });
+			this.send({
+				message: request,
+				failed: (err) => {
+					this.callbacks.delete(id);
+					reject(err);
+				},
+			});
+		});
+	}
+
+	// stream makes a call to a server stream procedure.
+	stream<T>(name: string, data: object, options: CallOptions | undefined): ServerStream<T> {
+		const id = this.callbackID();
+		const s = new ServerStream<T>(this, id);
+		const err = this.callError(name);
+		if (err !== undefined) {
+			s.end(err);
+			return s;
+		}
+		const request = this.newRequest(name, data, options);
+		request.cb_id = id;
+		this.streams.set(id, s);
+		this.send({
+			message: request,
+			failed: (err) => {
+				this.streams.delete(id);
+				s.end(err);
+			},
+		});
+		return s;
+	}
+
+	// subscribe makes a call to a server subscribe procedure. When valueName is set, the subscription value is that return value.
+	subscribe<T>(name: string, data: object, options: CallOptions | undefined, valueName?: string): Subscription<T> {
+		return new ServerSubscription<T>(this.stream<any>(name, data, options), valueName);
+	}
+
+	// sendMessage sends a message, or places it on the queue when the connection is not running yet.
+	sendMessage(messageObj: Message): void {
+		this.send({ message: messageObj });
+	}
+
+	// removeStream stops delivering items for a cancelled stream.
+	removeStream(id: number): void {
+		this.streams.delete(id);
+	}
+
+	// removeProducer stops a client stream that ended.
+	removeProducer(id: number): void {
+		this.producers.delete(id);
+	}
+
+	// handleRequest runs the handler for a request and sends the response.
+	handleRequest(messageObj: Message, handler: () => any): void {
+		Promise.resolve()
+			.then(handler)
+			.then(
+				(rets) => this.sendMessage({ type: "res", cb_id: messageObj.cb_id, data: rets ?? {} }),
+				(err) =>
+					this.sendMessage({
+						type: "res",
+						cb_id: messageObj.cb_id,
+						error: { type: "errorReturned", message: errorMessage(err) },
+					}),
+			);
+	}
+
+	// handleOneway runs the handler for a oneway request, there is no response to report errors with.
+	handleOneway(messageObj: Message, handler: () => void): void {
+		try {
+			handler();
+		} catch (err) {
+			console.error("ango: handler for " + messageObj.procedure + " failed:", err);
+		}
+	}
+
+	// handleStream runs the handler for a client stream procedure with a new stream, a thrown error ends the stream.
+	handleStream(messageObj: Message, handler: (stream: StreamSender<any>) => void): void {
+		const id = messageObj.cb_id as number;
+		const p = new ClientStream<any>(this, id);
+		this.producers.set(id, p);
+		try {
+			handler(p);
+		} catch (err) {
+			p.end(errorMessage(err));
+		}
+	}
+
+	// handleUnavailable rejects a request for a procedure that this client doesn't implement.
+	handleUnavailable(messageObj: Message): void {
+		if (messageObj.cb_id !== undefined) {
+			this.sendMessage({ type: "res", cb_id: messageObj.cb_id, error: errProcedureUnavailable });
+		}
+	}
+
+	private callbackID(): number {
+		this.currentCallbackID += 1;
+		return this.currentCallbackID;
+	}
+
+	// callError returns the error for a call that can't be made, or undefined.
+	// Before the handshake all procedures are assumed available, the server rejects the unavailable ones.
+	private callError(name: string): AngoError | undefined {
+		switch (this.state) {
+		case "stopped":
+			return this.stopError;
+		case "goingAway":
+			return errGoingAway;
+		}
+		if (this.available !== undefined && !this.available.has(name)) {
+			return errProcedureUnavailable;
+		}
+		return undefined;
+	}
+
+	// newRequest creates a request message, with meta from the trace context and the call options.
+	private newRequest(name: string, data: object, options: CallOptions | undefined): Message {
+		const request: Message = { type: "req", procedure: name, data: data };
+		const meta: Meta = {};
+		let hasMeta = false;
+		const tc = this.options.traceContext?.(name);
+		if (tc?.traceparent !== undefined) {
+			meta.traceparent = tc.traceparent;
+			hasMeta = true;
+		}
+		if (tc?.baggage !== undefined) {
+			meta.baggage = tc.baggage;
+			hasMeta = true;
+		}
+		// meta given in the call options takes precedence
+		const callMeta: Meta = options?.meta ?? {};
+		for (const key of Object.keys(callMeta)) {
+			meta[key] = callMeta[key];
+			hasMeta = true;
+		}
+		if (hasMeta) {
+			request.meta = meta;
+		}
+		return request;
+	}
+
+	// send sends the message for a queue item. Items sent within the same tick are coalesced into a single batch message.
+	// When the connection is not running yet, the item is placed on the queue.
+	private send(item: QueueItem): void {
+		if (this.state == "init") {
+			this.queue.push(item);
+			return;
+		}
+		if (this.state == "stopped") {
+			item.failed?.(errConnectionClosed);
+			return;
+		}
+		if (this.batch.length == 0) {
+			setTimeout(() => this.sendItems(this.batch.splice(0, this.batch.length)), 0);
+		}
+		this.batch.push(item);
+	}
+
+	// sendItems writes the messages for items to the websocket, multiple messages are written as a single batch message.
+	private sendItems(items: QueueItem[]): void {
+		if (this.state == "stopped") {
+			this.fail(items, errConnectionClosed);
+			return;
+		}
+		if (items.length == 1) {
+			this.ws.send(JSON.stringify(items[0].message));
+		} else if (items.length > 1) {
+			this.ws.send(JSON.stringify({ type: "batch", data: items.map((item) => item.message) }));
+		}
+		for (const item of items) {
+			item.sent?.();
+		}
+	}
+
+	private fail(items: QueueItem[], err: AngoError): void {
+		for (const item of items) {
+			item.failed?.(err);
+		}
+	}
+
+	// receive handles a message from the websocket, the first message is the answer to the handshake.
+	private receive(data: unknown): void {
+		if (typeof data != "string") {
+			// only the json codec is offered
+			return;
+		}
+		if (this.state != "init") {
+			this.handleMessage(JSON.parse(data));
+			return;
+		}
+		// "good" can be followed by the codec the server accepted and, when the versions differ, the server's signatures
+		const handshake = data.split(" ");
+		if (handshake[0] != "good") {
+			// the server doesn't accept this version
+			this.stop(errVersionMismatch);
+			this.ws.close();
+			return;
+		}
+		if (handshake.length > 2) {
+			this.available = new Set<string>();
+			const own = parseSignatures(signatures);
+			const other = parseSignatures(handshake[2]);
+			own.server.forEach((sig, name) => {
+				if (other.server.get(name) === sig) {
+					this.available!.add(name);
+				}
+			});
+		}
+		this.state = "running";
+		this.resolveReady();
+		this.sendItems(this.queue.splice(0, this.queue.length));
+	}
+
+	private handleMessage(messageObj: Message): void {
+		switch (messageObj.type) {
+		case "res":
+			this.handleResponse(messageObj);
+			break;
+		case "req":
+			this.dispatch(messageObj);
+			break;
+		case "goingAway":
+			this.handleGoingAway();
+			break;
+		case "item":
+			this.streams.get(messageObj.cb_id as number)?.item(messageObj.data);
+			break;
+		case "end":
+			this.handleStreamEnd(messageObj);
+			break;
+		case "cancel":
+			this.producers.get(messageObj.cb_id as number)?.cancelled();
+			break;
+		case "credit":
+			this.producers.get(messageObj.cb_id as number)?.credited(messageObj.data.credit);
+			break;
+		case "batch":
+			// a batch holds multiple messages, e.g. the responses to a batch of requests
+			for (const m of messageObj.data as Message[]) {
+				this.handleMessage(m);
+			}
+			break;
+		}
+	}
+
+	// handleResponse resolves or rejects a pending request
+	private handleResponse(messageObj: Message): void {
+		const id = messageObj.cb_id as number;
+		const cb = this.callbacks.get(id);
+		if (cb === undefined) {
+			return;
+		}
+		this.callbacks.delete(id);
+		if (typeof messageObj.error == "object" && messageObj.error !== null) {
+			cb.reject(toAngoError(messageObj.error));
+		} else {
+			cb.resolve(messageObj.data ?? undefined);
+		}
+	}
+
+	// handleStreamEnd completes a server stream, or ends it with an error
+	private handleStreamEnd(messageObj: Message): void {
+		const id = messageObj.cb_id as number;
+		const s = this.streams.get(id);
+		if (s === undefined) {
+			return;
+		}
+		this.streams.delete(id);
+		if (messageObj.error !== undefined && messageObj.error !== null) {
+			s.end(toAngoError(messageObj.error));
+		} else {
+			s.end(undefined);
+		}
+	}
+
+	// handleGoingAway handles the notice that the server is shutting down.
+	// Pending requests are still answered, queued and new requests are rejected.
+	private handleGoingAway(): void {
+		this.state = "goingAway";
+		this.fail(this.queue.splice(0, this.queue.length), errGoingAway);
+		this.options.onGoingAway?.();
+	}
+
+	// closed handles the end of the connection
+	private closed(): void {
+		if (this.state != "stopped") {
+			this.stop(errConnectionClosed);
+		}
+		if (!this.onCloseCalled) {
+			this.onCloseCalled = true;
+			this.options.onClose?.();
+		}
+	}
+
+	// stop rejects everything that is pending with err.
+	private stop(err: AngoError): void {
+		this.state = "stopped";
+		this.stopError = err;
+		this.rejectReady(err);
+		this.fail(this.queue.splice(0, this.queue.length), err);
+		this.fail(this.batch.splice(0, this.batch.length), err);
+		this.callbacks.forEach((cb) => cb.reject(err));
+		this.callbacks.clear();
+		this.streams.forEach((s) => s.end(err));
+		this.streams.clear();
+		this.producers.forEach((p) => p.cancelled());
+		this.producers.clear();
+	}
+}
+
+// ServerStream receives the items of a server stream. Credit is granted to the server as the items are consumed.
+class ServerStream<T> implements Stream<T>, StreamConsumer {
+	private readonly conn: Connection;
+	private readonly id: number;
+	private readonly items: T[] = [];
+	private waiting: ((result: IteratorResult<T>) => void) | undefined;
+	private waitingReject: ((err: AngoError) => void) | undefined;
+	private ended = false;
+	private error: AngoError | undefined;
+	private consumed = 0;
+
+	constructor(conn: Connection, id: number) {
+		this.conn = conn;
+		this.id = id;
+	}
+
+	[Symbol.asyncIterator](): AsyncIterator<T> {
+		return this;
+	}
+
+	async next(): Promise<IteratorResult<T>> {
+		if (this.items.length > 0) {
+			const value = this.items.shift() as T;
+			this.consume();
+			return { value: value, done: false };
+		}
+		if (this.error !== undefined) {
+			throw this.error;
+		}
+		if (this.ended) {
+			return { value: undefined, done: true };
+		}
+		return new Promise<IteratorResult<T>>((resolve, reject) => {
+			this.waiting = resolve;
+			this.waitingReject = reject;
+		});
+	}
+
+	// return is called when a for await loop is left early
+	async return(): Promise<IteratorResult<T>> {
+		this.cancel();
+		return { value: undefined, done: true };
+	}
+
+	cancel(): void {
+		if (this.ended) {
+			return;
+		}
+		this.conn.removeStream(this.id);
+		this.conn.sendMessage({ type: "cancel", cb_id: this.id });
+		this.items.length = 0;
+		this.end(undefined);
+	}
+
+	item(data: any): void {
+		if (this.ended) {
+			return;
+		}
+		if (this.waiting !== undefined) {
+			const resolve = this.waiting;
+			this.waiting = undefined;
+			this.waitingReject = undefined;
+			this.consume();
+			resolve({ value: data, done: false });
+			return;
+		}
+		this.items.push(data);
+	}
+
+	end(err: AngoError | undefined): void {
+		if (this.ended) {
+			return;
+		}
+		this.ended = true;
+		this.error = err;
+		if (this.waiting === undefined) {
+			return;
+		}
+		const resolve = this.waiting;
+		const reject = this.waitingReject!;
+		this.waiting = undefined;
+		this.waitingReject = undefined;
+		if (err !== undefined) {
+			reject(err);
+		} else {
+			resolve({ value: undefined, done: true });
+		}
+	}
+
+	// consume grants credit to the server when half the window was consumed
+	private consume(): void {
+		this.consumed++;
+		if (this.consumed >= streamWindow / 2 && !this.ended) {
+			this.conn.sendMessage({ type: "credit", cb_id: this.id, data: { credit: this.consumed } });
+			this.consumed = 0;
+		}
+	}
+}
+
+// ServerSubscription consumes a server stream and keeps the latest value.
+class ServerSubscription<T> implements Subscription<T> {
+	value: T | undefined = undefined;
+	private readonly stream: ServerStream<any>;
+	private readonly updateFns: ((value: T) => void)[] = [];
+	private readonly errorFns: ((err: AngoError) => void)[] = [];
+	private readonly endFns: (() => void)[] = [];
+
+	constructor(stream: ServerStream<any>, valueName: string | undefined) {
+		this.stream = stream;
+		this.run(valueName);
+	}
+
+	onUpdate(fn: (value: T) => void): Subscription<T> {
+		this.updateFns.push(fn);
+		return this;
+	}
+
+	onError(fn: (err: AngoError) => void): Subscription<T> {
+		this.errorFns.push(fn);
+		return this;
+	}
+
+	onEnd(fn: () => void): Subscription<T> {
+		this.endFns.push(fn);
+		return this;
+	}
+
+	unsubscribe(): void {
+		this.stream.cancel();
+	}
+
+	private async run(valueName: string | undefined): Promise<void> {
+		try {
+			for await (const item of this.stream) {
+				const value = (valueName === undefined ? item : item[valueName]) as T;
+				this.value = value;
+				this.updateFns.forEach((fn) => fn(value));
+			}
+		} catch (err) {
+			this.errorFns.forEach((fn) => fn(err as AngoError));
+			return;
+		}
+		this.endFns.forEach((fn) => fn());
+	}
+}
+
+// ClientStream sends the items of a client stream procedure call, as long as the server has granted credit.
+class ClientStream<T> implements StreamSender<T> {
+	private readonly conn: Connection;
+	private readonly id: number;
+	private credit = streamWindow;
+	private pending: T[] = [];
+	private ending = false;
+	private ended = false;
+	private endError: string | undefined;
+	private wasCancelled = false;
+	private readonly cancelFns: (() => void)[] = [];
+
+	constructor(conn: Connection, id: number) {
+		this.conn = conn;
+		this.id = id;
+	}
+
+	send(item: T): boolean {
+		if (this.wasCancelled || this.ending) {
+			return false;
+		}
+		this.pending.push(item);
+		this.flush();
+		return true;
+	}
+
+	end(error?: string): void {
+		if (this.wasCancelled || this.ending) {
+			return;
+		}
+		this.ending = true;
+		this.endError = error;
+		this.flush();
+	}
+
+	onCancel(fn: () => void): void {
+		this.cancelFns.push(fn);
+	}
+
+	isCancelled(): boolean {
+		return this.wasCancelled;
+	}
+
+	// cancelled stops the stream, the server cancelled it or the connection closed
+	cancelled(): void {
+		if (this.wasCancelled || this.ended) {
+			return;
+		}
+		this.conn.removeProducer(this.id);
+		this.wasCancelled = true;
+		this.pending = [];
+		this.cancelFns.forEach((fn) => fn());
+	}
+
+	credited(credit: number): void {
+		this.credit += credit;
+		this.flush();
+	}
+
+	// flush sends pending items while there is credit, and ends the stream when requested.
+	private flush(): void {
+		if (this.ended) {
+			return;
+		}
+		while (this.credit > 0 && this.pending.length > 0) {
+			this.credit--;
+			this.conn.sendMessage({ type: "item", cb_id: this.id, data: this.pending.shift() });
+		}
+		if (this.ending && this.pending.length == 0) {
+			this.ended = true;
+			this.conn.removeProducer(this.id);
+			const endMsg: Message = { type: "end", cb_id: this.id };
+			if (this.endError !== undefined) {
+				endMsg.error = { type: "errorReturned", message: this.endError };
+			}
+			this.conn.sendMessage(endMsg);
+		}
+	}
+}
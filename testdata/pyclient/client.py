@@ -0,0 +1,72 @@
+"""Calls the server started by TestPythonClient with the generated client: client.py <url> <codec>"""
+
+import queue
+import sys
+
+import pyclient_gen as pc
+
+
+class Handler(pc.PyclientHandler):
+    def __init__(self):
+        self.notifications = queue.Queue()
+
+    def notified(self, text, options):
+        self.notifications.put(text)
+
+    def ask(self, question, options):
+        if question == "fail":
+            raise Exception("no answer")
+        return pc.AskResult(answer="answer to " + question)
+
+
+def check(condition, format, *args):
+    if not condition:
+        raise AssertionError(format % args)
+
+
+def check_error(fn, type, message):
+    try:
+        fn()
+    except pc.AngoError as err:
+        check(err.type == type and err.message == message, "got %r, expected %s %r", err, type, message)
+    else:
+        raise AssertionError("expected %s %r" % (type, message))
+
+
+def main(url, codec):
+    handler = Handler()
+    with pc.PyclientClient(url, handler, codec=codec, timeout=10) as client:
+        check(client.codec == codec, "connected with codec %s", client.codec)
+
+        # calls
+        check(client.add(1, 2).c == 3, "add(1, 2) returned %r", client.add(1, 2))
+        p = pc.Point(x=1, y=-2, raw=bytes([0, 1, 255]), labels={1: "one", -3: "minus three"})
+        out = client.echo(p).out
+        check(out == p, "echo returned %r", out)
+        out = client.echo(pc.Point()).out
+        check(out == pc.Point(), "echo of empty point returned %r", out)
+
+        # errors
+        check_error(lambda: client.add(-1, 2), "errorReturned", "negative")
+        check_error(lambda: list(client.count(-1)), "errorReturned", "negative count")
+
+        # oneway procedures, and calls from the server to the client
+        client.notify("life")
+        text = handler.notifications.get(timeout=10)
+        check(text == "answer to life", "notified with %r", text)
+        client.notify("fail")
+        text = handler.notifications.get(timeout=10)
+        check(text == "error: no answer", "notified with %r", text)
+
+        # more items than the stream window, so credit is granted
+        items = [item.i for item in client.count(100)]
+        check(items == list(range(100)), "count sent %r", items)
+
+        # values may be skipped, but the last value arrives before the subscription ends
+        values = list(client.counter(100))
+        check(values and values[-1] == 100, "counter published %r", values)
+        check(values == sorted(set(values)), "counter published %r", values)
+
+
+if __name__ == "__main__":
+    main(sys.argv[1], sys.argv[2])
@@ -1,8 +1,13 @@
 package main
 
 import (
+	"fmt"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"time"
+
+	"github.com/GeertJohan/go.ask"
 )
 
 // randomString generates a pseudo-random alpha-numeric string with given length.
@@ -20,3 +25,25 @@ func randomString(length int) string {
 	}
 	return string(k)
 }
+
+// createOutputFile creates the file at outputFileAbs, and the directories leading to it.
+// When the file exists, it is truncated after the user agreed to overwrite it (or with --force-overwrite).
+func createOutputFile(outputFileAbs string, forceOverwrite bool) (*os.File, error) {
+	err := os.MkdirAll(filepath.Dir(outputFileAbs), 0755)
+	if err != nil {
+		return nil, err
+	}
+	outputFile, err := os.OpenFile(outputFileAbs, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+	if err != nil {
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		// output file exists, ask user if we should overwrite.
+		if !forceOverwrite && !ask.MustAskf("File '%s' exists, overwrite?", outputFileAbs) {
+			fmt.Println("Won't continue.")
+			os.Exit(1)
+		}
+		return os.OpenFile(outputFileAbs, os.O_TRUNC|os.O_WRONLY, 0666)
+	}
+	return outputFile, nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateOutputFile(t *testing.T) {
+	outputFileAbs := filepath.Join(t.TempDir(), "missing", "dir", "service.gen.js")
+	for _, content := range []string{"first run, longer content", "second run"} {
+		// the second call overwrites the file created by the first
+		outputFile, err := createOutputFile(outputFileAbs, true)
+		if err != nil {
+			t.Fatalf("error creating output file: %v", err)
+		}
+		_, err = outputFile.WriteString(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = outputFile.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		written, err := ioutil.ReadFile(outputFileAbs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(written) != content {
+			t.Fatalf("output file holds %q, expected %q", written, content)
+		}
+	}
+}